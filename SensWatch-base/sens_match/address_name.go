@@ -0,0 +1,296 @@
+package main
+
+import (
+	_ "embed"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 内置词典，每行一个词，# 开头的行为注释
+var (
+	//go:embed dict/divisions.txt
+	divisionsDict string
+	//go:embed dict/surnames.txt
+	surnamesDict string
+)
+
+// commonSurnameCount 姓氏表前多少个单姓可用于没有上下文提示的独立姓名识别
+const commonSurnameCount = 100
+
+// addrHan 地址中允许出现的汉字，排除常见虚词和动词，避免把普通句子并入地址
+const addrHan = `[^\P{Han}的是了在和与及或我你他她它们这那个有住于到去从往至为]`
+
+var (
+	// addressTailPattern 匹配行政区划地名之后的地址部分：行政区划后缀、下级区划、街道门牌和楼栋房间
+	addressTailPattern = regexp.MustCompile(`^(特别行政区|自治区|自治州|新区|地区|省|市|区|县|盟)?` +
+		`((?:` + addrHan + `{1,5}?(?:自治县|自治州|自治区|开发区|新区|地区|街道|社区|园区|省|市|区|县|旗|镇|乡|村|盟))*` +
+		`(?:` + addrHan + `{1,8}?(?:大道|大街|胡同|路|街|巷|弄)(?:[0-9０-９一二三四五六七八九十百零]+号院?)?)?` +
+		`(?:[0-9A-Za-z]+(?:号楼|栋|幢|座|单元|层|楼|室))*)`)
+
+	// streetPattern 匹配没有行政区划前缀的“××路××号”
+	streetPattern = regexp.MustCompile(addrHan + `{2,8}?(?:大道|大街|胡同|路|街|巷|弄)[0-9０-９一二三四五六七八九十百零]+号院?` +
+		`(?:[0-9A-Za-z]+(?:号楼|栋|幢|座|单元|层|楼|室))*`)
+
+	// nameCuePattern 匹配姓名前常见的提示词
+	nameCuePattern = regexp.MustCompile(`(?:姓名|联系人|负责人|申请人|收件人|收货人|寄件人|经办人|法定代表人|法人代表|户主|持卡人|开户名|户名|签字|签名|投保人|被保险人|患者|学生|员工)[:：\s]*`)
+
+	// nameTitlePattern 匹配姓名后常见的称谓
+	nameTitlePattern = regexp.MustCompile(`先生|女士|同志|老师|教授|博士|经理|主任|医生|律师|总监`)
+)
+
+// nameStopPrefixes 姓名之后常见的词，遇到时姓名在此结束
+var nameStopPrefixes = []string{
+	"电话", "手机", "身份", "性别", "先生", "女士", "地址", "住址", "联系", "年龄", "出生", "籍贯",
+	"民族", "邮箱", "职务", "证件", "男", "女", "的", "是", "在", "和", "与", "及", "等", "说", "于", "为",
+}
+
+// nonNameChars 很少出现在名字里、却常见于以姓氏开头的普通词语中的字，只用于独立姓名识别
+const nonNameChars = "的了是在有和与及或等个们这那额式期量度号码数率性类型称址业务区市省县路街色年日时分秒元件表单格费价款位部门局处科室院校厂司店行案向法面围事况物品器具子头上下后内外间里边口州族证卡账户机话箱龄籍贯职历额计划"
+
+// separatorRunes 列表项或表格单元格的分隔符，独立姓名两侧必须是这些字符
+const separatorRunes = "\n\r\t-、，,;；|：:"
+
+// AddressName 处理地址和人名识别
+type AddressName struct {
+	divisions      map[string]bool
+	maxDivision    int // 最长地名的字符数
+	surnames       map[string]bool
+	commonSurnames map[string]bool
+}
+
+// NewAddressName 根据内置词典创建新的 AddressName 实例
+func NewAddressName() *AddressName {
+	a := &AddressName{
+		divisions:      make(map[string]bool),
+		surnames:       make(map[string]bool),
+		commonSurnames: make(map[string]bool),
+	}
+	for _, name := range dictWords(divisionsDict) {
+		a.divisions[name] = true
+		if n := utf8.RuneCountInString(name); n > a.maxDivision {
+			a.maxDivision = n
+		}
+	}
+	single := 0
+	for _, name := range dictWords(surnamesDict) {
+		a.surnames[name] = true
+		if utf8.RuneCountInString(name) == 1 && single < commonSurnameCount {
+			a.commonSurnames[name] = true
+			single++
+		}
+	}
+	return a
+}
+
+// sharedAddressName 所有 SensMatch 共享的识别器，构造后只读，可以并发使用
+var sharedAddressName = sync.OnceValue(NewAddressName)
+
+// dictWords 解析词典内容
+func dictWords(dict string) []string {
+	var words []string
+	for _, line := range strings.Split(dict, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words
+}
+
+// Find 返回文本中识别出的地址和姓名，按偏移排序
+func (a *AddressName) Find(text string) []Match {
+	matches := a.findAddresses(text)
+	matches = append(matches, a.findNames(text, matches)...)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Offset < matches[j].Offset })
+	return matches
+}
+
+// findAddresses 以行政区划地名为起点识别地址
+func (a *AddressName) findAddresses(text string) []Match {
+	var matches []Match
+	for i := 0; i < len(text); {
+		name := a.divisionAt(text[i:])
+		if name == "" {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+
+		// 只有地名（或地名加后缀）不算地址，后面至少还要有一级
+		loc := addressTailPattern.FindStringSubmatchIndex(text[i+len(name):])
+		if loc == nil || loc[5] == loc[4] {
+			i += len(name)
+			continue
+		}
+		end := i + len(name) + loc[1]
+		matches = append(matches, Match{Value: text[i:end], Offset: int64(i)})
+		i = end
+	}
+
+	for _, loc := range streetPattern.FindAllStringIndex(text, -1) {
+		if !overlaps(matches, loc[0], loc[1]) {
+			matches = append(matches, Match{Value: text[loc[0]:loc[1]], Offset: int64(loc[0])})
+		}
+	}
+	return matches
+}
+
+// divisionAt 返回 text 开头最长的行政区划地名
+func (a *AddressName) divisionAt(text string) string {
+	var ends []int
+	for i, n := 0, 0; i < len(text) && n < a.maxDivision; n++ {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.Is(unicode.Han, r) {
+			break
+		}
+		i += size
+		ends = append(ends, i)
+	}
+	for k := len(ends) - 1; k >= 1; k-- {
+		if a.divisions[text[:ends[k]]] {
+			return text[:ends[k]]
+		}
+	}
+	return ""
+}
+
+// findNames 识别姓名：提示词之后、称谓之前，以及单独成项的姓名
+func (a *AddressName) findNames(text string, addresses []Match) []Match {
+	var matches []Match
+	add := func(start, end int) {
+		if end > start && !overlaps(addresses, start, end) && !overlaps(matches, start, end) {
+			matches = append(matches, Match{Value: text[start:end], Offset: int64(start)})
+		}
+	}
+
+	for _, loc := range nameCuePattern.FindAllStringIndex(text, -1) {
+		add(loc[1], loc[1]+a.nameAt(text[loc[1]:]))
+	}
+
+	for _, loc := range nameTitlePattern.FindAllStringIndex(text, -1) {
+		// 向前尝试三个字和两个字的姓名
+		for _, back := range []int{3, 2} {
+			start := loc[0]
+			for k := 0; k < back && start > 0; k++ {
+				_, size := utf8.DecodeLastRuneInString(text[:start])
+				start -= size
+			}
+			if a.nameAt(text[start:loc[0]]) == loc[0]-start {
+				add(start, loc[0])
+				break
+			}
+		}
+	}
+
+	// 表格单元格或列表项中单独出现的姓名
+	for start := 0; start < len(text); {
+		r, size := utf8.DecodeRuneInString(text[start:])
+		if !unicode.Is(unicode.Han, r) {
+			start += size
+			continue
+		}
+		end := start
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !unicode.Is(unicode.Han, r) {
+				break
+			}
+			end += size
+		}
+		if a.isStandaloneName(text, start, end) {
+			add(start, end)
+		}
+		start = end
+	}
+	return matches
+}
+
+// nameAt 尝试在 text 开头识别姓名，返回姓名的字节长度，0 表示不是姓名
+func (a *AddressName) nameAt(text string) int {
+	surname := 0
+	if r1, s1 := utf8.DecodeRuneInString(text); s1 > 0 {
+		if _, s2 := utf8.DecodeRuneInString(text[s1:]); s2 > 0 && a.surnames[text[:s1+s2]] {
+			surname = s1 + s2
+		} else if a.surnames[string(r1)] {
+			surname = s1
+		}
+	}
+	if surname == 0 {
+		return 0
+	}
+
+	end := surname
+	for given := 0; given < 2; given++ {
+		rest := text[end:]
+		if hasNameStopPrefix(rest) {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		if size == 0 || !unicode.Is(unicode.Han, r) {
+			break
+		}
+		end += size
+	}
+	if end == surname {
+		return 0
+	}
+	return end
+}
+
+// isStandaloneName 判断 [start, end) 这段汉字是否是单独成项的姓名
+func (a *AddressName) isStandaloneName(text string, start, end int) bool {
+	token := text[start:end]
+	n := utf8.RuneCountInString(token)
+	if n < 2 || n > 3 || a.divisions[token] {
+		return false
+	}
+	first, size := utf8.DecodeRuneInString(token)
+	if !a.commonSurnames[string(first)] || strings.ContainsAny(token[size:], nonNameChars) {
+		return false
+	}
+	if a.nameAt(token) != len(token) {
+		return false
+	}
+
+	// 两侧跳过空格后必须是行首行尾或分隔符
+	before := strings.TrimRight(text[:start], " ")
+	after := strings.TrimLeft(text[end:], " ")
+	if before != "" {
+		r, _ := utf8.DecodeLastRuneInString(before)
+		if !strings.ContainsRune(separatorRunes, r) {
+			return false
+		}
+	}
+	if after != "" {
+		r, _ := utf8.DecodeRuneInString(after)
+		if !strings.ContainsRune(separatorRunes, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasNameStopPrefix 判断 text 是否以姓名之后常见的词开头
+func hasNameStopPrefix(text string) bool {
+	for _, p := range nameStopPrefixes {
+		if strings.HasPrefix(text, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps 判断 [start, end) 是否与已有命中重叠
+func overlaps(matches []Match, start, end int) bool {
+	for _, m := range matches {
+		mStart := int(m.Offset)
+		if start < mStart+len(m.Value) && mStart < end {
+			return true
+		}
+	}
+	return false
+}
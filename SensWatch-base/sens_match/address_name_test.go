@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddressNameFind(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"完整地址", "住址：北京市海淀区中关村大街27号1101室，电话13800000000", []string{"北京市海淀区中关村大街27号1101室"}},
+		{"只有行政区划", "浙江省杭州市西湖区", []string{"浙江省杭州市西湖区"}},
+		{"没有行政区划的门牌", "人民路100号3栋", []string{"人民路100号3栋"}},
+		{"提示词之后的姓名", "收件人：张三丰电话13800000000", []string{"张三丰"}},
+		{"称谓之前的姓名", "请联系王小明先生办理", []string{"王小明"}},
+		{"单元格中的姓名", "姓名\t张伟\t男", []string{"张伟"}},
+		{"列表项中的姓名", "序号、李娜、销售部", []string{"李娜"}},
+		{"地名出现在普通句子中", "上海的天气很好", nil},
+		{"姓氏开头的普通词语", "张开会议室", nil},
+	}
+	a := NewAddressName()
+	for _, tt := range tests {
+		var got []string
+		for _, m := range a.Find(tt.text) {
+			if tt.text[m.Offset:int(m.Offset)+len(m.Value)] != m.Value {
+				t.Errorf("%s: 偏移 %d 处不是 %q", tt.name, m.Offset, m.Value)
+			}
+			got = append(got, m.Value)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Find(%q) = %q，期望 %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveLimits 展开压缩包时的限制，防止压缩炸弹耗尽磁盘和时间
+type ArchiveLimits struct {
+	MaxDepth      int     // 最多展开几层压缩包，1 表示只展开文件本身，<= 0 表示不展开压缩包
+	MaxMembers    int     // 一个压缩包（包括嵌套的压缩包）最多扫描的成员数
+	MaxRatio      float64 // 成员解压后大小与压缩后大小之比的上限
+	MaxTotalBytes int64   // 一个压缩包（包括嵌套的压缩包）解压后的总大小上限
+	IncludeHidden bool    // 是否扫描压缩包中的隐藏文件和临时文件，与遍历目录的 -hidden 参数一致
+}
+
+// defaultArchiveLimits 默认的压缩包限制
+var defaultArchiveLimits = ArchiveLimits{
+	MaxDepth:      3,
+	MaxMembers:    10000,
+	MaxRatio:      100,
+	MaxTotalBytes: 1 << 30,
+}
+
+// ratioFloor 小于该大小的成员不检查压缩比，很小的文件压缩比高是正常的
+const ratioFloor = 1 << 20
+
+// archiveSeparator 虚拟路径中压缩包与成员之间的分隔符，如 backup.zip!/hr/staff.xlsx
+const archiveSeparator = "!/"
+
+// isArchive 返回该类型是否为可以展开的压缩包
+func isArchive(t ContentType) bool {
+	return t == TypeZip || t == TypeTar || t == TypeGzip
+}
+
+// SetArchiveLimits 设置展开压缩包时的限制
+func (p *FileProcessor) SetArchiveLimits(limits ArchiveLimits) {
+	p.archiveLimits = limits
+}
+
+// archiveLimitError 表示压缩包超出了限制，已展开的成员的命中仍然保留
+type archiveLimitError string
+
+func (e archiveLimitError) Error() string { return string(e) }
+
+// archiveLeaf 处理解压出的一个不是压缩包的成员。tmp 为解压出的临时文件，name 为成员在压缩包中的文件名，
+// virtual 为成员的虚拟路径，depth 为成员所在压缩包的层数
+type archiveLeaf func(tmp string, ctype ContentType, name, virtual string, depth int) error
+
+// archiveWalker 递归展开一个压缩包，把其中的每个成员交给 leaf 处理
+type archiveWalker struct {
+	limits  ArchiveLimits
+	leaf    archiveLeaf
+	members int
+	total   int64
+}
+
+// nestedLimits 返回成员内部再次展开压缩包（如压缩包中邮件的附件）时的限制，
+// 扣除已经展开的层数、成员数和解压大小，嵌套的文件不能绕过外层的限制
+func (w *archiveWalker) nestedLimits(depth int) ArchiveLimits {
+	limits := w.limits
+	limits.MaxDepth -= depth
+	limits.MaxMembers -= w.members
+	limits.MaxTotalBytes -= w.total
+	return limits
+}
+
+// scanArchive 展开压缩包并扫描所有成员，命中的 Location.Member 为成员的虚拟路径。
+// 超出限制时停止展开，返回已经扫描到的命中和扩展名与内容不符的成员
+func (p *FileProcessor) scanArchive(filePath string, ctype ContentType) (map[string][]Match, []Match, error) {
+	found := make(map[string][]Match)
+	var mismatches []Match // 扩展名与内容不符的成员，不参与最小命中次数过滤和打码
+	nest := func(m Match, virtual string) Match {
+		var inner Location
+		if m.Location != nil {
+			inner = *m.Location
+		}
+		loc := nestLocation(Location{Member: virtual}, inner)
+		m.Location = &loc
+		return m
+	}
+
+	w := &archiveWalker{limits: p.archiveLimits}
+	w.leaf = func(tmp string, ctype ContentType, name, virtual string, depth int) error {
+		if extensionMismatch(name, ctype) {
+			m := typeMismatchMatch(name, ctype)
+			m.Location = &Location{Member: virtual}
+			mismatches = append(mismatches, m)
+		}
+		if !ctype.Scannable() {
+			return nil
+		}
+
+		memberFound, memberMismatches, err := p.scanContent(tmp, ctype, w.nestedLimits(depth))
+		if err != nil {
+			fmt.Printf("处理压缩包成员 %s 失败: %v\n", virtual, err)
+			return nil
+		}
+		for ruleID, matches := range memberFound {
+			for _, m := range matches {
+				found[ruleID] = append(found[ruleID], nest(m, virtual))
+			}
+		}
+		for _, m := range memberMismatches {
+			mismatches = append(mismatches, nest(m, virtual))
+		}
+		return nil
+	}
+
+	err := w.walkArchive(filePath, ctype, filepath.Base(filePath), 1)
+	if _, ok := err.(archiveLimitError); ok {
+		fmt.Printf("压缩包 %s 超出限制，停止展开: %v\n", filePath, err)
+		err = nil
+	}
+	return found, mismatches, err
+}
+
+// walkArchive 按类型展开压缩包，virtual 为压缩包自身的虚拟路径，depth 为压缩包所在的层数
+func (w *archiveWalker) walkArchive(filePath string, ctype ContentType, virtual string, depth int) error {
+	switch ctype {
+	case TypeZip:
+		return w.walkZip(filePath, virtual, depth)
+	case TypeTar:
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("打开压缩包失败: %v", err)
+		}
+		defer f.Close()
+		return w.walkTar(f, virtual, depth)
+	case TypeGzip:
+		return w.walkGzip(filePath, virtual, depth)
+	}
+	return nil
+}
+
+// walkZip 逐个解压 zip 成员
+func (w *archiveWalker) walkZip(filePath, virtual string, depth int) error {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("打开zip文件失败: %v", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			fmt.Printf("打开压缩包成员 %s 失败: %v\n", virtual+archiveSeparator+f.Name, err)
+			continue
+		}
+		err = w.member(rc, f.Name, int64(f.CompressedSize64), virtual, depth)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar 逐个读取 tar 成员，只处理普通文件
+func (w *archiveWalker) walkTar(r io.Reader, virtual string, depth int) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取tar文件失败: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// tar 不压缩，成员大小就是占用的空间，不检查压缩比
+		if err := w.member(tr, hdr.Name, -1, virtual, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// walkGzip 解压 gzip 文件。gzip 只包含一个文件，解压结果不增加虚拟路径的层级，
+// 如 backup.tar.gz 中的成员为 backup.tar.gz!/hr/staff.xlsx
+func (w *archiveWalker) walkGzip(filePath, virtual string, depth int) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %v", err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("打开gzip文件失败: %v", err)
+	}
+	defer gz.Close()
+
+	tmp, size, err := w.extract(gz, virtual, stat.Size())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	ctype, err := sniffFile(tmp)
+	if err != nil {
+		return err
+	}
+	if isArchive(ctype) {
+		return w.expand(tmp, ctype, size, virtual, depth)
+	}
+	name := path.Base(virtual)
+	if ext := path.Ext(name); strings.EqualFold(ext, ".gz") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if gz.Name != "" {
+		name = gz.Name
+	}
+	return w.leaf(tmp, ctype, name, virtual, depth)
+}
+
+// member 解压一个成员到临时文件后扫描，成员本身是压缩包时继续展开。
+// compressed 为成员压缩后的大小，< 0 表示不检查压缩比
+func (w *archiveWalker) member(r io.Reader, name string, compressed int64, parent string, depth int) error {
+	w.members++
+	if w.members > w.limits.MaxMembers {
+		return archiveLimitError(fmt.Sprintf("成员数超过 %d", w.limits.MaxMembers))
+	}
+	virtual := parent + archiveSeparator + strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !w.limits.IncludeHidden && isTempOrHiddenFile(name) {
+		return nil
+	}
+
+	tmp, size, err := w.extract(r, virtual, compressed)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	ctype, err := sniffFile(tmp)
+	if err != nil {
+		fmt.Printf("识别压缩包成员 %s 的类型失败: %v\n", virtual, err)
+		return nil
+	}
+	if isArchive(ctype) {
+		return w.expand(tmp, ctype, size, virtual, depth)
+	}
+	return w.leaf(tmp, ctype, name, virtual, depth)
+}
+
+// expand 展开解压出的嵌套压缩包，size 为其解压出的大小。展开后压缩包本身不再计入解压总大小，
+// 只计入其中的成员，避免 tar.gz 解压出的 tar 和 tar 中的成员被重复计算
+func (w *archiveWalker) expand(tmp string, ctype ContentType, size int64, virtual string, depth int) error {
+	if depth >= w.limits.MaxDepth {
+		fmt.Printf("压缩包嵌套超过 %d 层，不再展开: %s\n", w.limits.MaxDepth, virtual)
+		return nil
+	}
+	w.total -= size
+	return w.walkArchive(tmp, ctype, virtual, depth+1)
+}
+
+// extract 把数据写入私有临时目录中的临时文件，同时检查压缩比和解压总大小，返回临时文件路径和解压出的大小
+func (w *archiveWalker) extract(r io.Reader, virtual string, compressed int64) (string, int64, error) {
+	limit := w.limits.MaxTotalBytes - w.total
+	if compressed >= 0 {
+		ratioLimit := int64(float64(compressed) * w.limits.MaxRatio)
+		if ratioLimit < ratioFloor {
+			ratioLimit = ratioFloor
+		}
+		if ratioLimit < limit {
+			limit = ratioLimit
+		}
+	}
+
+	tmp, err := createScratchFile("archive_*")
+	if err != nil {
+		return "", 0, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	n, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("解压 %s 失败: %v", virtual, err)
+	}
+	if n > limit {
+		os.Remove(tmp.Name())
+		if w.total+n > w.limits.MaxTotalBytes {
+			return "", 0, archiveLimitError(fmt.Sprintf("解压后总大小超过 %d 字节", w.limits.MaxTotalBytes))
+		}
+		return "", 0, archiveLimitError(fmt.Sprintf("%s 的压缩比超过 %.0f", virtual, w.limits.MaxRatio))
+	}
+	w.total += n
+	return tmp.Name(), n, nil
+}
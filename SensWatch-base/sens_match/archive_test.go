@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTarGz 在 dir 下创建 tar.gz 压缩包，entries 依次为成员名和内容
+func writeTarGz(t *testing.T, dir, name string, entries ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i+1 < len(entries); i += 2 {
+		hdr := &tar.Header{Name: entries[i], Mode: 0600, Size: int64(len(entries[i+1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entries[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, dir, name, buf.String())
+}
+
+// archiveMembers 返回手机号命中所在的成员虚拟路径，按路径排序
+func archiveMembers(t *testing.T, limits ArchiveLimits, path string) []string {
+	t.Helper()
+	defer removeScratch()
+	p := NewFileProcessor()
+	p.SetArchiveLimits(limits)
+	ctype, err := sniffFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, _, err := p.scanArchive(path, ctype)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members []string
+	for _, m := range found["phone"] {
+		members = append(members, m.Location.Member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func TestScanArchive(t *testing.T) {
+	dir := t.TempDir()
+	inner := readFileString(t, writeZip(t, dir, "inner.zip", "b.txt", "电话13612345678"))
+	tgz := readFileString(t, writeTarGz(t, dir, "logs.tar.gz", "var/log/c.txt", "电话13512345678"))
+	path := writeZip(t, dir, "backup.zip",
+		"hr/a.txt", "电话13812345678",
+		"./hr/../hr/d.txt", "电话13712345678",
+		"nested/inner.zip", inner,
+		"logs.tar.gz", tgz,
+		"hr/~$a.docx", "电话13912345678",
+		".hidden.txt", "电话13012345678",
+	)
+	big := writeTarGz(t, dir, "big.tar.gz", "big.txt", "电话13812345678"+strings.Repeat("x", 40<<10))
+	bomb := writeZip(t, dir, "bomb.zip",
+		"a.txt", "电话13812345678",
+		"zeros.txt", strings.Repeat("0", 2<<20),
+		"z.txt", "电话13612345678",
+	)
+
+	hidden := defaultArchiveLimits
+	hidden.IncludeHidden = true
+	shallow := defaultArchiveLimits
+	shallow.MaxDepth = 1
+	few := defaultArchiveLimits
+	few.MaxMembers = 2
+	tight := defaultArchiveLimits
+	tight.MaxTotalBytes = 64 << 10
+
+	tests := []struct {
+		name   string
+		limits ArchiveLimits
+		path   string
+		want   []string
+	}{
+		{"默认限制", defaultArchiveLimits, path, []string{
+			"backup.zip!/hr/a.txt",
+			"backup.zip!/hr/d.txt",
+			"backup.zip!/logs.tar.gz!/var/log/c.txt",
+			"backup.zip!/nested/inner.zip!/b.txt",
+		}},
+		{"包括隐藏文件", hidden, path, []string{
+			"backup.zip!/.hidden.txt",
+			"backup.zip!/hr/a.txt",
+			"backup.zip!/hr/d.txt",
+			"backup.zip!/hr/~$a.docx",
+			"backup.zip!/logs.tar.gz!/var/log/c.txt",
+			"backup.zip!/nested/inner.zip!/b.txt",
+		}},
+		{"只展开一层", shallow, path, []string{"backup.zip!/hr/a.txt", "backup.zip!/hr/d.txt"}},
+		{"成员数超出限制", few, path, []string{"backup.zip!/hr/a.txt", "backup.zip!/hr/d.txt"}},
+		{"压缩比超出限制时保留已扫描的成员", defaultArchiveLimits, bomb, []string{"bomb.zip!/a.txt"}},
+		// tar.gz 解压出的 tar 和其中的成员只计算一次
+		{"tar.gz 的解压大小", tight, big, []string{"big.tar.gz!/big.txt"}},
+	}
+	for _, tt := range tests {
+		if got := archiveMembers(t, tt.limits, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 命中的成员为 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanArchiveCleansUp(t *testing.T) {
+	dir := t.TempDir()
+	path := writeZip(t, dir, "a.zip", "a.txt", "电话13812345678", "b.txt", "电话13612345678")
+	defer removeScratch()
+
+	p := NewFileProcessor()
+	if _, _, err := p.scanArchive(path, TypeZip); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := scratchDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("扫描后临时目录中还剩 %d 个文件", len(entries))
+	}
+	if filepath.Dir(dir) != scratchRoot {
+		t.Errorf("临时目录 %s 不在 %s 下", dir, scratchRoot)
+	}
+}
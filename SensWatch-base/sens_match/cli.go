@@ -0,0 +1,583 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `用法: sens_match [命令] [参数]
+
+命令:
+  run            全量扫描后持续监控文件变化（不带命令时的默认行为）
+  scan           全量扫描文件索引（或 -path 指定的目录）中的文件，保存结果后退出
+  watch          只监控文件变化并同步到数据库
+  query <值>     查找包含指定敏感值的所有文件
+  export         把数据库中的检测结果导出为 JSON 或 CSV
+  rules list     列出检测策略中的规则
+  rules test     用检测规则测试一段文本或一个文件
+
+各命令的参数见 sens_match <命令> -h。
+路径参数默认相对于当前工作目录的上一级目录（与 main.py 启动时一致），可用 -root 修改。
+`
+
+// stringList 可以重复指定的字符串参数
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// cliConfig 各命令共用的路径和扫描参数
+type cliConfig struct {
+	root         string
+	index        string
+	output       string
+	db           string
+	policy       string
+	key          string
+	directoryLog string
+	workers      int
+	maxInFlight  int64
+	quiet        time.Duration
+	full         bool
+	dirs         stringList
+	paths        stringList
+	walk         WalkOptions
+	archive      ArchiveLimits
+}
+
+// registerPaths 注册路径参数
+func (c *cliConfig) registerPaths(fs *flag.FlagSet) {
+	fs.StringVar(&c.root, "root", "", "默认路径所在的目录，默认为当前工作目录的上一级")
+	fs.StringVar(&c.output, "output", "", "JSON 结果文件路径，默认 <root>/output.json")
+	fs.StringVar(&c.db, "db", "", "SQLite 数据库路径，默认与 JSON 结果文件同名的 .db 文件")
+	fs.StringVar(&c.policy, "rules", "", "检测策略（规则集）文件，默认 <root>/sens_policy.yaml，不存在时使用内置策略")
+	fs.StringVar(&c.key, "key", "", "指纹密钥文件，默认 <root>/sens_match.key")
+}
+
+// registerScan 注册全量扫描参数
+func (c *cliConfig) registerScan(fs *flag.FlagSet) {
+	fs.StringVar(&c.index, "index", "", "文件索引路径，默认 <root>/file_index.json")
+	fs.IntVar(&c.workers, "workers", 0, "并发扫描的文件数，默认为 CPU 核数")
+	fs.Int64Var(&c.maxInFlight, "max-inflight-bytes", defaultMaxInFlightBytes, "同时处理的文件总大小上限，0 表示不限制")
+	fs.BoolVar(&c.full, "full", false, "忽略增量扫描状态，重新扫描所有文件")
+	fs.Var(&c.paths, "path", "直接遍历的目录，可以重复指定；指定后不再读取文件索引")
+	fs.Var((*stringList)(&c.walk.Include), "include", "遍历目录时只扫描匹配的文件，可以重复指定，如 *.docx、**/hr/**")
+	fs.Var((*stringList)(&c.walk.Exclude), "exclude", "遍历目录时跳过匹配的文件和目录，可以重复指定")
+	fs.IntVar(&c.walk.MaxDepth, "max-depth", 0, "遍历目录的最大深度，目录下的文件深度为 1，0 表示不限制")
+	fs.BoolVar(&c.walk.IncludeHidden, "hidden", false, "遍历目录和展开压缩包时也扫描隐藏文件和临时文件")
+	fs.BoolVar(&c.walk.OneFileSystem, "one-file-system", false, "遍历目录时不进入其它文件系统")
+	fs.Func("symlinks", "遍历目录时符号链接的处理方式：skip 或 follow（默认 skip）", func(v string) error {
+		switch policy := SymlinkPolicy(v); policy {
+		case SymlinkSkip, SymlinkFollow:
+			c.walk.Symlinks = policy
+			return nil
+		}
+		return fmt.Errorf("未知的符号链接处理方式: %s，可选 skip 或 follow", v)
+	})
+}
+
+// registerArchive 注册压缩包参数
+func (c *cliConfig) registerArchive(fs *flag.FlagSet) {
+	fs.IntVar(&c.archive.MaxDepth, "archive-depth", defaultArchiveLimits.MaxDepth, "最多展开几层嵌套的压缩包，0 表示不扫描压缩包")
+	fs.IntVar(&c.archive.MaxMembers, "archive-max-members", defaultArchiveLimits.MaxMembers, "一个压缩包最多扫描的成员数")
+	fs.Float64Var(&c.archive.MaxRatio, "archive-max-ratio", defaultArchiveLimits.MaxRatio, "压缩包成员解压后与压缩后大小之比的上限")
+	fs.Int64Var(&c.archive.MaxTotalBytes, "archive-max-bytes", defaultArchiveLimits.MaxTotalBytes, "一个压缩包解压后的总大小上限")
+}
+
+// registerWatch 注册监控参数
+func (c *cliConfig) registerWatch(fs *flag.FlagSet) {
+	fs.Var(&c.dirs, "dir", "需要监控的目录，可以重复指定；默认读取目录记录")
+	fs.StringVar(&c.directoryLog, "directory-log", "", "read_path.py 写入的目录记录，默认 <root>/directory_log.json")
+	fs.DurationVar(&c.quiet, "quiet", defaultQuietPeriod, "文件安静多久后才扫描")
+}
+
+// resolve 根据 root 填充未指定的路径
+func (c *cliConfig) resolve() error {
+	if c.root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("获取当前工作目录失败: %v", err)
+		}
+		c.root = filepath.Dir(cwd)
+	}
+	def := func(p *string, name string) {
+		if *p == "" {
+			*p = filepath.Join(c.root, name)
+		}
+	}
+	def(&c.index, "file_index.json")
+	def(&c.output, "output.json")
+	def(&c.key, "sens_match.key")
+	def(&c.directoryLog, "directory_log.json")
+	if c.db == "" {
+		c.db = strings.TrimSuffix(c.output, filepath.Ext(c.output)) + ".db"
+	}
+	return nil
+}
+
+// loadPolicy 加载检测策略，没有策略文件时使用内置默认策略
+func (c *cliConfig) loadPolicy() (*Policy, error) {
+	if c.policy != "" {
+		return LoadPolicyFile(c.policy)
+	}
+	return LoadPolicyOrDefault(filepath.Join(c.root, "sens_policy.yaml"))
+}
+
+// newProcessor 根据策略和持久化的指纹密钥创建 FileProcessor，loadKey 决定密钥不存在时是否生成新密钥
+func (c *cliConfig) newProcessor(loadKey func(path string) ([]byte, error)) (*FileProcessor, error) {
+	policy, err := c.loadPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("加载检测策略失败: %v", err)
+	}
+	processor, err := NewFileProcessorWithPolicy(policy)
+	if err != nil {
+		return nil, fmt.Errorf("初始化检测规则失败: %v", err)
+	}
+
+	// 使用持久化的指纹密钥，保证多次运行的指纹一致
+	key, err := loadKey(c.key)
+	if err != nil {
+		return nil, fmt.Errorf("加载指纹密钥失败: %v", err)
+	}
+	masker, err := NewMasker(policy.Masking.Mode, key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化打码器失败: %v", err)
+	}
+	processor.SetMasker(masker)
+	processor.SetConcurrency(c.workers, c.maxInFlight)
+	c.archive.IncludeHidden = c.walk.IncludeHidden
+	processor.SetArchiveLimits(c.archive)
+	return processor, nil
+}
+
+// runCLI 解析命令行并执行命令，返回进程退出码
+func runCLI(args []string) int {
+	cmd := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	// 删除崩溃的进程留下的解压文件，退出时删除本进程的临时目录
+	removeStaleScratch(scratchRoot)
+	defer removeScratch()
+
+	var err error
+	switch cmd {
+	case "run":
+		err = cmdRun(args)
+	case "scan":
+		err = cmdScan(args)
+	case "watch":
+		err = cmdWatch(args)
+	case "query":
+		err = cmdQuery(args)
+	case "export":
+		err = cmdExport(args)
+	case "rules":
+		err = cmdRules(args)
+	case "help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Printf("未知命令: %s\n\n%s", cmd, usage)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if _, ok := err.(usageError); ok {
+		fmt.Println(err)
+		return 2
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// usageError 表示命令行参数错误
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// newFlagSet 创建子命令的参数集合
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: sens_match %s\n\n参数:\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// cmdRun 全量扫描后持续监控
+func cmdRun(args []string) error {
+	var c cliConfig
+	fs := newFlagSet("run", "run [参数]")
+	c.registerPaths(fs)
+	c.registerScan(fs)
+	c.registerArchive(fs)
+	c.registerWatch(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+
+	processor, err := c.newProcessor(LoadOrCreateMaskKey)
+	if err != nil {
+		return err
+	}
+	if state := c.openScanState(processor); state != nil {
+		defer state.Close()
+	}
+	fileList, err := c.scan(processor)
+	if err != nil {
+		return err
+	}
+	return c.watch(processor, fileList)
+}
+
+// cmdScan 全量扫描后退出，适合在定时任务中运行
+func cmdScan(args []string) error {
+	var c cliConfig
+	fs := newFlagSet("scan", "scan [参数]")
+	c.registerPaths(fs)
+	c.registerScan(fs)
+	c.registerArchive(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+	processor, err := c.newProcessor(LoadOrCreateMaskKey)
+	if err != nil {
+		return err
+	}
+	if state := c.openScanState(processor); state != nil {
+		defer state.Close()
+	}
+	_, err = c.scan(processor)
+	return err
+}
+
+// cmdWatch 只监控文件变化
+func cmdWatch(args []string) error {
+	var c cliConfig
+	fs := newFlagSet("watch", "watch [参数]")
+	c.registerPaths(fs)
+	c.registerArchive(fs)
+	c.registerWatch(fs)
+	fs.StringVar(&c.index, "index", "", "没有目录记录时，从文件索引推导需要监控的目录，默认 <root>/file_index.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+
+	processor, err := c.newProcessor(LoadOrCreateMaskKey)
+	if err != nil {
+		return err
+	}
+	state, err := OpenScanState(c.db)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+	processor.SetScanState(state)
+
+	var fileList []FileInfo
+	if len(c.dirs) == 0 {
+		if _, err := os.Stat(c.directoryLog); err != nil {
+			if fileList, err = ReadFileList(c.index); err != nil {
+				return fmt.Errorf("读取文件列表失败: %v", err)
+			}
+		}
+	}
+	return c.watch(processor, fileList)
+}
+
+// openScanState 打开扫描状态并启用增量扫描，跳过上次扫描后没有变化的文件。
+// 打开失败时返回 nil，完整扫描所有文件；调用方负责关闭返回的状态
+func (c *cliConfig) openScanState(processor *FileProcessor) *ScanState {
+	state, err := OpenScanState(c.db)
+	if err != nil {
+		fmt.Printf("打开扫描状态失败，将完整扫描所有文件: %v\n", err)
+		return nil
+	}
+	if c.full {
+		state.Prune(nil)
+	}
+	processor.SetScanState(state)
+	return state
+}
+
+// scan 读取文件索引或遍历目录并全量扫描，保存结果
+func (c *cliConfig) scan(processor *FileProcessor) ([]FileInfo, error) {
+	// 读取输入文件列表，指定了 -path 时直接遍历目录。roots 为文件列表覆盖的目录，
+	// 只清理这些目录下的旧记录，不影响其它目录
+	var fileList []FileInfo
+	var roots []string
+	var err error
+	if len(c.paths) > 0 {
+		if fileList, err = WalkFiles(c.paths, c.walk); err != nil {
+			return nil, fmt.Errorf("遍历目录失败: %v", err)
+		}
+		fmt.Printf("遍历目录完成，共 %d 个文件\n", len(fileList))
+		roots = c.paths
+	} else {
+		if fileList, err = ReadFileList(c.index); err != nil {
+			return nil, fmt.Errorf("读取文件列表失败: %v", err)
+		}
+		if roots, err = ReadWatchRoots(c.directoryLog); err != nil {
+			roots = watchRootsFromFiles(fileList)
+		}
+	}
+
+	results, scope := processor.ProcessFileList(fileList, roots)
+
+	// 保存结果
+	if err := processor.SaveResults(results, scope, c.output, c.db); err != nil {
+		return nil, fmt.Errorf("保存结果失败: %v", err)
+	}
+
+	fmt.Printf("处理完成，结果已保存到: %s\n", c.output)
+	return fileList, nil
+}
+
+// watch 监控 -dir 指定的目录；没有指定时监控 -path 遍历的目录或已建立索引的目录，
+// 都没有时监控文件列表所在的目录
+func (c *cliConfig) watch(processor *FileProcessor, fileList []FileInfo) error {
+	roots := []string(c.dirs)
+	if len(roots) == 0 {
+		roots = c.paths
+	}
+	if len(roots) == 0 {
+		var err error
+		if roots, err = ReadWatchRoots(c.directoryLog); err != nil {
+			roots = watchRootsFromFiles(fileList)
+		}
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("没有需要监控的目录，请用 -dir 指定")
+	}
+	realTimeMatch(processor, roots, c.db, c.quiet)
+	return nil
+}
+
+// cmdQuery 查找包含指定敏感值的文件
+func cmdQuery(args []string) error {
+	var c cliConfig
+	var asJSON bool
+	fs := newFlagSet("query", "query [参数] <敏感值>")
+	c.registerPaths(fs)
+	fs.BoolVar(&asJSON, "json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageError("需要指定一个要查询的敏感值")
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+
+	// 查询不生成新密钥，否则新密钥计算的指纹查不到任何已有结果
+	processor, err := c.newProcessor(LoadMaskKey)
+	if err != nil {
+		return err
+	}
+	value := fs.Arg(0)
+	hits, err := processor.QueryValue(c.db, value)
+	if err != nil {
+		return fmt.Errorf("查询失败: %v", err)
+	}
+	if asJSON {
+		return writeJSON(os.Stdout, hits)
+	}
+	printQueryHits(value, hits)
+	return nil
+}
+
+// cmdExport 导出数据库中的检测结果
+func cmdExport(args []string) error {
+	var c cliConfig
+	var format, out string
+	fs := newFlagSet("export", "export [参数]")
+	c.registerPaths(fs)
+	fs.StringVar(&format, "format", "json", "导出格式：json 或 csv")
+	fs.StringVar(&out, "o", "", "导出文件路径，默认输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if format != "json" && format != "csv" {
+		return usageError("不支持的导出格式: " + format)
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+
+	results, err := LoadResults(c.db)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("创建导出文件失败: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "json" {
+		return writeJSON(w, results)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file_path", "file_name", "md5", "detect_time", "total_sensitive_count", "rule_numbers"})
+	for _, r := range results {
+		cw.Write([]string{r.FilePath, r.FileName, r.MD5, r.DetectTime, strconv.Itoa(r.TotalSensitiveCount), r.RuleNumbers})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cmdRules 列出或测试检测规则
+func cmdRules(args []string) error {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "test") {
+		return usageError("用法: sens_match rules list|test [参数]")
+	}
+	sub, args := args[0], args[1:]
+
+	var c cliConfig
+	var file string
+	fs := newFlagSet("rules "+sub, "rules "+sub+" [参数]")
+	fs.StringVar(&c.root, "root", "", "默认路径所在的目录，默认为当前工作目录的上一级")
+	fs.StringVar(&c.policy, "rules", "", "检测策略（规则集）文件，默认 <root>/sens_policy.yaml，不存在时使用内置策略")
+	if sub == "test" {
+		fs.StringVar(&file, "file", "", "测试的文件，不指定时测试命令行中的文本")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "用法: sens_match rules test [参数] [文本...]\n\n参数:\n")
+			fs.PrintDefaults()
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.resolve(); err != nil {
+		return err
+	}
+	policy, err := c.loadPolicy()
+	if err != nil {
+		return fmt.Errorf("加载检测策略失败: %v", err)
+	}
+	s, err := NewSensMatchWithPolicy(policy)
+	if err != nil {
+		return fmt.Errorf("初始化检测规则失败: %v", err)
+	}
+
+	if sub == "list" {
+		printRules(policy, s.Registry())
+		return nil
+	}
+
+	var r io.Reader
+	switch {
+	case file != "":
+		reader, err := GetFileReader(file)
+		if err != nil {
+			return fmt.Errorf("获取文件读取器失败: %v", err)
+		}
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+		r = reader
+	case fs.NArg() > 0:
+		r = strings.NewReader(strings.Join(fs.Args(), " "))
+	default:
+		fs.Usage()
+		return usageError("需要指定要测试的文本或 -file")
+	}
+
+	found, err := NewStreamScanner(s, defaultBufferSize, defaultOverlap).Scan(r)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+	printRuleTest(s.Registry(), found)
+	return nil
+}
+
+// printRules 按编号列出策略中的所有规则
+func printRules(policy *Policy, registry *RuleRegistry) {
+	fmt.Printf("策略版本: %s（规则集 %s）\n", policy.Version, policy.RuleSetVersion())
+	fmt.Printf("%-4s %-14s %-16s %-7s %-5s %-6s %s\n", "编号", "标识", "名称", "等级", "启用", "最小命中", "打码")
+	for _, rule := range registry.AllRules() {
+		enabled := "是"
+		if !registry.Enabled(rule.ID()) {
+			enabled = "否"
+		}
+		fmt.Printf("%-4d %-14s %-16s %-7s %-5s %-6d %s\n",
+			rule.Number(), rule.ID(), rule.Name(), rule.Severity(), enabled, rule.MinHits(), rule.Mask())
+	}
+}
+
+// printRuleTest 输出规则测试结果。测试的是调用方自己提供的样本，因此显示原值
+func printRuleTest(registry *RuleRegistry, found map[string][]Match) {
+	if len(found) == 0 {
+		fmt.Println("没有规则命中")
+		return
+	}
+	var ids []string
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ni, _ := registry.RuleNumber(ids[i])
+		nj, _ := registry.RuleNumber(ids[j])
+		return ni < nj
+	})
+
+	for _, id := range ids {
+		rule, _ := registry.Lookup(id)
+		matches := found[id]
+		note := ""
+		if len(matches) < rule.MinHits() {
+			note = fmt.Sprintf("（未达到最小命中次数 %d，扫描时不会上报）", rule.MinHits())
+		}
+		fmt.Printf("[%d] %s %s: %d 处%s\n", rule.Number(), id, rule.Name(), len(matches), note)
+		for _, m := range matches {
+			where := fmt.Sprintf("第%d行 偏移%d", m.Line, m.Offset)
+			if m.Location != nil {
+				where = m.Location.String() + " " + where
+			}
+			fmt.Printf("    %s  %s\n", m.Value, where)
+		}
+	}
+}
+
+// writeJSON 以缩进格式输出 JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return fmt.Errorf("序列化结果失败: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
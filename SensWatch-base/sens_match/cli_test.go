@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinksFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SymlinkPolicy
+		wantErr bool
+	}{
+		{"skip", SymlinkSkip, false},
+		{"follow", SymlinkFollow, false},
+		{"Follow", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		var c cliConfig
+		fs := newFlagSet("scan", "scan [参数]")
+		fs.SetOutput(io.Discard)
+		c.registerScan(fs)
+		err := fs.Parse([]string{"-symlinks", tt.value})
+		if (err != nil) != tt.wantErr || c.walk.Symlinks != tt.want {
+			t.Errorf("-symlinks %q: 得到 %q，err = %v", tt.value, c.walk.Symlinks, err)
+		}
+	}
+}
+
+func TestSaveResultsReturnsDatabaseError(t *testing.T) {
+	dir := t.TempDir()
+	p := NewFileProcessor()
+	results, scope := p.ProcessFileList([]FileInfo{{Path: writeFile(t, dir, "a.txt", "电话13812345678")}}, []string{dir})
+	// 数据库路径是一个目录，无法打开
+	if err := p.SaveResults(results, scope, filepath.Join(dir, "output.json"), dir); err == nil {
+		t.Error("写入数据库失败时应当返回错误")
+	}
+}
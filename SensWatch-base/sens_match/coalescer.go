@@ -0,0 +1,255 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultQuietPeriod 文件在最后一次变化后需要保持不变多久才会被扫描
+const defaultQuietPeriod = 2 * time.Second
+
+// editorTempSuffixes 编辑器和下载工具保存文件时使用的临时文件后缀。
+// .bak 等备份文件是长期保留的副本，需要扫描，不在其中
+var editorTempSuffixes = []string{".tmp", ".temp", ".swp", ".swx", ".part", ".crdownload", "~"}
+
+// isTempOrHiddenFile 判断是否为临时文件或隐藏文件，与 read_path.py 中的同名函数一致，
+// 遍历目录和展开压缩包时使用
+func isTempOrHiddenFile(filePath string) bool {
+	if filePath == "" {
+		return false
+	}
+	base := filepath.Base(filePath)
+	return strings.HasPrefix(base, "~") ||
+		strings.HasPrefix(base, ".~") ||
+		strings.HasPrefix(base, ".") ||
+		base == ".DS_Store" ||
+		strings.HasSuffix(base, ".pdf.txt")
+}
+
+// isEditorTempFile 判断文件变化事件是否来自临时文件。在 isTempOrHiddenFile 的基础上
+// 增加了编辑器保存过程中短暂存在的临时文件，如 ~WRL0001.tmp、x.swp，只用于合并监控事件
+func isEditorTempFile(filePath string) bool {
+	if isTempOrHiddenFile(filePath) {
+		return true
+	}
+	lower := strings.ToLower(filepath.Base(filePath))
+	for _, suffix := range editorTempSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingFile 表示一个仍在变化中的文件
+type pendingFile struct {
+	origin   string // 这一批变化之前文件所在的路径，新建的文件为空
+	modified bool   // 内容是否可能有变化
+	last     time.Time
+}
+
+// Coalescer 合并短时间内的大量文件变化事件。
+// 保存 Office 文档时通常会产生多次修改和经过临时文件的重命名，
+// Coalescer 等文件安静一段时间后，把整条重命名链折叠成一个净变化，并丢弃临时文件
+type Coalescer struct {
+	quiet   time.Duration
+	files   map[string]*pendingFile // 当前路径 -> 变化中的文件
+	deleted map[string]time.Time    // 已删除的原路径
+	dirs    map[string]time.Time    // 已删除或移出的目录
+}
+
+// NewCoalescer 创建 Coalescer，quiet 为文件需要保持安静的时间
+func NewCoalescer(quiet time.Duration) *Coalescer {
+	return &Coalescer{
+		quiet:   quiet,
+		files:   make(map[string]*pendingFile),
+		deleted: make(map[string]time.Time),
+		dirs:    make(map[string]time.Time),
+	}
+}
+
+// Pending 返回尚未输出的变化数量
+func (c *Coalescer) Pending() int {
+	return len(c.files) + len(c.deleted) + len(c.dirs)
+}
+
+// Add 记录一个文件变化
+func (c *Coalescer) Add(change FileChange, now time.Time) {
+	switch change.Type {
+	case "create":
+		path := change.FilePath
+		if f, ok := c.files[path]; ok {
+			f.modified = true
+			f.last = now
+		} else if _, ok := c.deleted[path]; ok {
+			// 删除后又在原位置新建，相当于修改
+			delete(c.deleted, path)
+			c.files[path] = &pendingFile{origin: path, modified: true, last: now}
+		} else {
+			c.files[path] = &pendingFile{last: now}
+		}
+
+	case "modify":
+		path := change.FilePath
+		if f, ok := c.files[path]; ok {
+			f.modified = true
+			f.last = now
+		} else {
+			c.files[path] = &pendingFile{origin: path, modified: true, last: now}
+		}
+
+	case "delete":
+		if change.IsDir {
+			c.deleteDir(change.FilePath, now)
+			return
+		}
+		c.deleteFile(change.FilePath, now)
+
+	case "move":
+		f, ok := c.files[change.OldPath]
+		if ok {
+			delete(c.files, change.OldPath)
+		} else {
+			f = &pendingFile{origin: change.OldPath}
+		}
+		// 目标位置原有的文件被覆盖
+		if _, ok := c.files[change.NewPath]; ok {
+			c.deleteFile(change.NewPath, now)
+		}
+		if _, ok := c.deleted[change.NewPath]; ok && f.origin == "" {
+			// 新文件移动到刚被删除的位置，相当于修改
+			delete(c.deleted, change.NewPath)
+			f.origin = change.NewPath
+			f.modified = true
+		}
+		f.last = now
+		c.files[change.NewPath] = f
+	}
+}
+
+// deleteFile 记录文件被删除。这一批中新建的文件被删除时不留下任何变化
+func (c *Coalescer) deleteFile(path string, now time.Time) {
+	origin := path
+	if f, ok := c.files[path]; ok {
+		delete(c.files, path)
+		origin = f.origin
+	}
+	if origin != "" {
+		c.deleted[origin] = now
+	}
+}
+
+// deleteDir 记录目录被删除或移出，目录下的变化一并丢弃
+func (c *Coalescer) deleteDir(dir string, now time.Time) {
+	prefix := dir + string(filepath.Separator)
+	for path, f := range c.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		delete(c.files, path)
+		// 从目录外移入的文件，原路径上的记录也要删除
+		if f.origin != "" && !strings.HasPrefix(f.origin, prefix) {
+			c.deleted[f.origin] = now
+		}
+	}
+	for path := range c.deleted {
+		if strings.HasPrefix(path, prefix) {
+			delete(c.deleted, path)
+		}
+	}
+	c.dirs[dir] = now
+}
+
+// settled 返回路径上的所有变化是否都已安静了足够长时间
+func (c *Coalescer) settled(path string, now time.Time) bool {
+	if f, ok := c.files[path]; ok && now.Sub(f.last) < c.quiet {
+		return false
+	}
+	if at, ok := c.deleted[path]; ok && now.Sub(at) < c.quiet {
+		return false
+	}
+	return true
+}
+
+// Ready 取出已经安静下来的净变化。删除排在移动之前，移动排在新建和修改之前，
+// 保证按顺序处理时目标路径上的旧记录已被清理
+func (c *Coalescer) Ready(now time.Time) []FileChange {
+	var dirDeletes, deletes, moves, updates []FileChange
+
+	for dir, at := range c.dirs {
+		if now.Sub(at) >= c.quiet {
+			delete(c.dirs, dir)
+			dirDeletes = append(dirDeletes, FileChange{Type: "delete", FilePath: dir, IsDir: true})
+		}
+	}
+
+	for path, at := range c.deleted {
+		if now.Sub(at) < c.quiet || !c.settled(path, now) {
+			continue
+		}
+		// 原位置上又出现了文件，由下面的循环作为修改输出
+		if _, ok := c.files[path]; ok {
+			continue
+		}
+		delete(c.deleted, path)
+		if !isEditorTempFile(path) {
+			deletes = append(deletes, FileChange{Type: "delete", FilePath: path})
+		}
+	}
+
+	for path, f := range c.files {
+		if !c.settled(path, now) || (f.origin != "" && f.origin != path && !c.settled(f.origin, now)) {
+			continue
+		}
+		delete(c.files, path)
+		origin := f.origin
+		if _, ok := c.deleted[path]; ok {
+			// 原文件被删除后同一路径上又出现了文件
+			delete(c.deleted, path)
+			if origin == "" || isEditorTempFile(origin) {
+				origin = path
+			} else {
+				deletes = append(deletes, FileChange{Type: "delete", FilePath: path})
+			}
+		}
+		// 临时文件不扫描；原文件最终落在临时文件上，说明它被删除了
+		if isEditorTempFile(path) {
+			if origin != "" && !isEditorTempFile(origin) {
+				deletes = append(deletes, FileChange{Type: "delete", FilePath: origin})
+			}
+			continue
+		}
+		// 由临时文件重命名而来的文件按新建处理
+		if isEditorTempFile(origin) {
+			origin = ""
+		}
+
+		switch {
+		case origin == "":
+			updates = append(updates, FileChange{Type: "create", FilePath: path})
+		case origin == path:
+			if f.modified {
+				updates = append(updates, FileChange{Type: "modify", FilePath: path})
+			}
+		default:
+			moves = append(moves, FileChange{Type: "move", OldPath: origin, NewPath: path})
+		}
+	}
+
+	sortChanges(dirDeletes)
+	sortChanges(deletes)
+	sortChanges(moves)
+	sortChanges(updates)
+	changes := append(dirDeletes, deletes...)
+	changes = append(changes, moves...)
+	return append(changes, updates...)
+}
+
+// sortChanges 按路径排序，保证输出顺序稳定
+func sortChanges(changes []FileChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FilePath+changes[i].NewPath < changes[j].FilePath+changes[j].NewPath
+	})
+}
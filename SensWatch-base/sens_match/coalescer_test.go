@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalescer(t *testing.T) {
+	dir := filepath.Join("data", "docs")
+	p := func(name string) string { return filepath.Join(dir, name) }
+	create := func(name string) FileChange { return FileChange{Type: "create", FilePath: p(name)} }
+	modify := func(name string) FileChange { return FileChange{Type: "modify", FilePath: p(name)} }
+	remove := func(name string) FileChange { return FileChange{Type: "delete", FilePath: p(name)} }
+	move := func(from, to string) FileChange { return FileChange{Type: "move", OldPath: p(from), NewPath: p(to)} }
+
+	tests := []struct {
+		name    string
+		changes []FileChange
+		want    []FileChange
+	}{
+		{"Word 保存", []FileChange{
+			create("~$合同.docx"),
+			create("~WRD0001.tmp"), modify("~WRD0001.tmp"),
+			move("合同.docx", "~WRL0001.tmp"),
+			move("~WRD0001.tmp", "合同.docx"),
+			remove("~WRL0001.tmp"),
+			remove("~$合同.docx"),
+		}, []FileChange{modify("合同.docx")}},
+		{"先写临时文件再改名", []FileChange{
+			create("a.txt.swp"), modify("a.txt.swp"), move("a.txt.swp", "a.txt"),
+		}, []FileChange{create("a.txt")}},
+		{"删除后在原位置重建", []FileChange{remove("a.txt"), create("a.txt")}, []FileChange{modify("a.txt")}},
+		{"新建后删除", []FileChange{create("a.txt"), modify("a.txt"), remove("a.txt")}, nil},
+		{"连续重命名", []FileChange{move("a.txt", "b.txt"), move("b.txt", "c.txt")}, []FileChange{move("a.txt", "c.txt")}},
+		{"重命名后又改回", []FileChange{move("a.txt", "b.txt"), move("b.txt", "a.txt")}, nil},
+		{"重命名覆盖刚修改过的文件", []FileChange{modify("b.txt"), move("a.txt", "b.txt")},
+			[]FileChange{remove("b.txt"), move("a.txt", "b.txt")}},
+		{"重命名覆盖刚新建的文件", []FileChange{create("b.txt"), modify("b.txt"), move("a.txt", "b.txt")},
+			[]FileChange{move("a.txt", "b.txt")}},
+		{"重命名为临时文件", []FileChange{move("a.txt", "a.txt~")}, []FileChange{remove("a.txt")}},
+		{"删除目录", []FileChange{
+			create(filepath.Join("sub", "a.txt")),
+			move("b.txt", filepath.Join("sub", "b.txt")),
+			{Type: "delete", FilePath: p("sub"), IsDir: true},
+		}, []FileChange{{Type: "delete", FilePath: p("sub"), IsDir: true}, remove("b.txt")}},
+	}
+
+	start := time.Now()
+	for _, tt := range tests {
+		c := NewCoalescer(time.Second)
+		for i, change := range tt.changes {
+			c.Add(change, start.Add(time.Duration(i)*100*time.Millisecond))
+		}
+		last := start.Add(time.Duration(len(tt.changes)-1) * 100 * time.Millisecond)
+		if got := c.Ready(last.Add(500 * time.Millisecond)); len(got) != 0 {
+			t.Errorf("%s: 安静期内输出了 %+v", tt.name, got)
+		}
+		got := c.Ready(last.Add(time.Second))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Ready() = %+v，期望 %+v", tt.name, got, tt.want)
+		}
+		if c.Pending() != 0 {
+			t.Errorf("%s: 输出后还有 %d 个未处理的变化", tt.name, c.Pending())
+		}
+	}
+}
+
+func TestTempFileFilters(t *testing.T) {
+	tests := []struct {
+		name         string
+		tempOrHidden bool
+		editorTemp   bool
+	}{
+		{"~$合同.docx", true, true},
+		{".~lock.a.ods#", true, true},
+		{".hidden", true, true},
+		{"a.pdf.txt", true, true},
+		{"~WRL0001.tmp", true, true},
+		{"WRD0001.TMP", false, true},
+		{"a.txt.swp", false, true},
+		{"a.docx.crdownload", false, true},
+		{"a.txt~", false, true},
+		{"a.bak", false, false},
+		{"合同.docx", false, false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join("data", tt.name)
+		if got := isTempOrHiddenFile(path); got != tt.tempOrHidden {
+			t.Errorf("isTempOrHiddenFile(%q) = %v", tt.name, got)
+		}
+		if got := isEditorTempFile(path); got != tt.editorTemp {
+			t.Errorf("isEditorTempFile(%q) = %v", tt.name, got)
+		}
+	}
+}
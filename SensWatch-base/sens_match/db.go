@@ -4,86 +4,260 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// ExportToSQLite 导出数据到SQLite数据库文件
-func ExportToSQLite(sqlitePath string, results []SensitiveInfo) error {
-	// 创建SQLite数据库连接
-	sqliteDB, err := sql.Open("sqlite3", sqlitePath)
+// upsertResultSQL 写入或更新一个文件的检测结果。使用 upsert 而不是 INSERT OR REPLACE，
+// 已有记录的 id、created_at 以及界面或其它工具添加的列都会保留
+const upsertResultSQL = `
+INSERT INTO detection_results
+(file_path, file_name, md5, detect_time, match_counts, matches, match_details, total_sensitive_count, rule_numbers)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(file_path) DO UPDATE SET
+	file_name = excluded.file_name,
+	md5 = excluded.md5,
+	detect_time = excluded.detect_time,
+	match_counts = excluded.match_counts,
+	matches = excluded.matches,
+	match_details = excluded.match_details,
+	total_sensitive_count = excluded.total_sensitive_count,
+	rule_numbers = excluded.rule_numbers
+`
+
+// insertMatchSQL 写入一条命中明细
+const insertMatchSQL = `
+INSERT INTO matches
+(file_id, rule_id, value, fingerprint, byte_offset, line, page, sheet, cell, slide, member, message_id, attachment, part, context)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// resultWriter 在一个事务中写入检测结果、命中明细和审计事件
+type resultWriter struct {
+	tx          *sql.Tx
+	upsert      *sql.Stmt
+	insertMatch *sql.Stmt
+	source      string
+}
+
+// newResultWriter 创建 resultWriter，source 为写入审计事件的来源
+func newResultWriter(tx *sql.Tx, source string) (*resultWriter, error) {
+	upsert, err := tx.Prepare(upsertResultSQL)
 	if err != nil {
-		return fmt.Errorf("创建SQLite数据库失败: %v", err)
+		return nil, fmt.Errorf("准备SQLite插入语句失败: %v", err)
 	}
-	defer sqliteDB.Close()
+	insertMatch, err := tx.Prepare(insertMatchSQL)
+	if err != nil {
+		upsert.Close()
+		return nil, fmt.Errorf("准备SQLite插入语句失败: %v", err)
+	}
+	return &resultWriter{tx: tx, upsert: upsert, insertMatch: insertMatch, source: source}, nil
+}
 
-	// 先删除旧表
-	dropTableSQL := `DROP TABLE IF EXISTS detection_results;`
-	_, err = sqliteDB.Exec(dropTableSQL)
+// Close 释放预编译的语句
+func (w *resultWriter) Close() {
+	w.upsert.Close()
+	w.insertMatch.Close()
+}
+
+// Write 写入一个文件的检测结果。复用缓存、没有重新扫描的结果不记录事件，
+// 数据库中的记录就来自这次缓存的扫描时也不重写命中明细
+func (w *resultWriter) Write(result SensitiveInfo) error {
+	prev, ok, err := currentResult(w.tx, result.FilePath)
+	if err != nil {
+		return err
+	}
+	rescanned := !ok || !result.cached
+	if rescanned {
+		if err := recordResultEvent(w.tx, ok, result, w.source); err != nil {
+			return err
+		}
+	}
+
+	// 将match_counts和matches转换为JSON字符串
+	matchCountsJSON, err := json.Marshal(result.MatchCounts)
 	if err != nil {
-		return fmt.Errorf("删除旧SQLite表失败: %v", err)
-	}
-
-	// 创建表
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS detection_results (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		file_path TEXT NOT NULL UNIQUE,
-		file_name TEXT NOT NULL,
-		md5 TEXT NOT NULL,
-		detect_time DATETIME NOT NULL,
-		match_counts TEXT,
-		matches TEXT,
-		total_sensitive_count INTEGER DEFAULT 0,
-		rule_numbers TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-	_, err = sqliteDB.Exec(createTableSQL)
+		return fmt.Errorf("转换match_counts为JSON失败: %v", err)
+	}
+
+	matchesJSON, err := json.Marshal(result.Matches)
 	if err != nil {
-		return fmt.Errorf("创建SQLite表失败: %v", err)
+		return fmt.Errorf("转换matches为JSON失败: %v", err)
 	}
 
-	// 准备SQLite插入语句
-	insertSQL := `
-	INSERT OR REPLACE INTO detection_results 
-	(file_path, file_name, md5, detect_time, match_counts, matches, total_sensitive_count, rule_numbers)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`
+	matchDetailsJSON, err := json.Marshal(result.MatchDetails)
+	if err != nil {
+		return fmt.Errorf("转换match_details为JSON失败: %v", err)
+	}
 
-	stmt, err := sqliteDB.Prepare(insertSQL)
+	// 执行插入或更新
+	_, err = w.upsert.Exec(
+		result.FilePath, // 使用完整路径
+		result.FileName, // 使用文件名
+		result.MD5,
+		result.DetectTime,
+		string(matchCountsJSON),
+		string(matchesJSON),
+		string(matchDetailsJSON),
+		result.TotalSensitiveCount,
+		result.RuleNumbers)
 	if err != nil {
-		return fmt.Errorf("准备SQLite插入语句失败: %v", err)
+		return fmt.Errorf("插入数据到SQLite失败: %v", err)
+	}
+
+	// 上次导出失败时，缓存的结果可能还没有写入数据库
+	if !rescanned && prev.DetectTime == result.DetectTime {
+		return nil
+	}
+
+	// 重写命中明细
+	var fileID int64
+	if err := w.tx.QueryRow("SELECT id FROM detection_results WHERE file_path = ?", result.FilePath).Scan(&fileID); err != nil {
+		return fmt.Errorf("查询数据库失败: %v", err)
+	}
+	if _, err := w.tx.Exec("DELETE FROM matches WHERE file_id = ?", fileID); err != nil {
+		return fmt.Errorf("删除命中明细失败: %v", err)
 	}
-	defer stmt.Close()
+	return insertMatches(w.insertMatch, fileID, result.MatchDetails)
+}
+
+// insertMatches 按规则标识的顺序写入一个文件的命中明细
+func insertMatches(stmt *sql.Stmt, fileID int64, details map[string][]Match) error {
+	ruleIDs := make([]string, 0, len(details))
+	for ruleID := range details {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+
+	for _, ruleID := range ruleIDs {
+		for _, m := range details[ruleID] {
+			var page, slide sql.NullInt64
+			var sheet, cell, member, messageID, attachment, part sql.NullString
+			if loc := m.Location; loc != nil {
+				page = sql.NullInt64{Int64: int64(loc.Page), Valid: loc.Page > 0}
+				slide = sql.NullInt64{Int64: int64(loc.Slide), Valid: loc.Slide > 0}
+				sheet = nullString(loc.Sheet)
+				cell = nullString(loc.Cell)
+				member = nullString(loc.Member)
+				messageID = nullString(loc.MessageID)
+				attachment = nullString(loc.Attachment)
+				part = nullString(loc.Part)
+			}
+			_, err := stmt.Exec(fileID, ruleID, m.Value, nullString(m.Fingerprint), m.Offset, m.Line,
+				page, sheet, cell, slide, member, messageID, attachment, part, nullString(m.Context))
+			if err != nil {
+				return fmt.Errorf("写入命中明细失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
 
-	// 从全局变量中获取所有结果
+// ExportToSQLite 导出数据到SQLite数据库文件。数据库中本次成功扫描且不再包含敏感信息的文件、
+// 以及本次遍历的目录下已不在文件列表中的文件删除其记录；扫描失败的文件和其它目录下的文件保留原有记录
+func ExportToSQLite(sqlitePath string, results []SensitiveInfo, scope *ScanScope) error {
+	// 打开SQLite数据库并升级到最新结构
+	sqliteDB, err := openDatabase(sqlitePath)
+	if err != nil {
+		return err
+	}
+	defer sqliteDB.Close()
+
+	// 开始事务
+	tx, err := sqliteDB.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	writer, err := newResultWriter(tx, SourceFullScan)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	sensitive := make(map[string]bool)
 	for _, result := range results {
-		// 将match_counts和matches转换为JSON字符串
-		matchCountsJSON, err := json.Marshal(result.MatchCounts)
-		if err != nil {
-			return fmt.Errorf("转换match_counts为JSON失败: %v", err)
+		if err := writer.Write(result); err != nil {
+			return err
 		}
+		sensitive[result.FilePath] = true
+	}
 
-		matchesJSON, err := json.Marshal(result.Matches)
-		if err != nil {
-			return fmt.Errorf("转换matches为JSON失败: %v", err)
+	// 找出不再包含敏感信息的文件
+	rows, err := tx.Query("SELECT file_path FROM detection_results")
+	if err != nil {
+		return fmt.Errorf("查询数据库失败: %v", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			rows.Close()
+			return fmt.Errorf("查询数据库失败: %v", err)
+		}
+		if !sensitive[filePath] && (scope.Clean[filePath] || scope.Gone(filePath)) {
+			stale = append(stale, filePath)
 		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询数据库失败: %v", err)
+	}
 
-		// 执行插入
-		_, err = stmt.Exec(
-			result.FilePath, // 使用完整路径
-			result.FileName, // 使用文件名
-			result.MD5,
-			result.DetectTime,
-			string(matchCountsJSON),
-			string(matchesJSON),
-			result.TotalSensitiveCount,
-			result.RuleNumbers)
-		if err != nil {
-			return fmt.Errorf("插入数据到SQLite失败: %v", err)
+	for _, filePath := range stale {
+		if err := recordRemovalEvent(tx, EventCleared, filePath, "", SourceFullScan); err != nil {
+			return err
 		}
+		if _, err := tx.Exec("DELETE FROM detection_results WHERE file_path = ?", filePath); err != nil {
+			return fmt.Errorf("删除文件记录失败: %v", err)
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
 	}
 
 	return nil
 }
+
+// LoadResults 从SQLite数据库读取所有检测结果，按文件路径排序
+func LoadResults(sqlitePath string) ([]SensitiveInfo, error) {
+	sqliteDB, err := openDatabase(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+	defer sqliteDB.Close()
+
+	rows, err := sqliteDB.Query(`
+	SELECT file_path, file_name, md5, CAST(detect_time AS TEXT), match_counts, matches, match_details,
+		total_sensitive_count, rule_numbers
+	FROM detection_results ORDER BY file_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SensitiveInfo
+	for rows.Next() {
+		var r SensitiveInfo
+		var matchCounts, matches, matchDetails, ruleNumbers sql.NullString
+		if err := rows.Scan(&r.FilePath, &r.FileName, &r.MD5, &r.DetectTime, &matchCounts, &matches, &matchDetails,
+			&r.TotalSensitiveCount, &ruleNumbers); err != nil {
+			return nil, fmt.Errorf("查询数据库失败: %v", err)
+		}
+		r.RuleNumbers = ruleNumbers.String
+		// JSON 列可能为空或由旧版本写入，解析失败时忽略
+		json.Unmarshal([]byte(matchCounts.String), &r.MatchCounts)
+		json.Unmarshal([]byte(matches.String), &r.Matches)
+		json.Unmarshal([]byte(matchDetails.String), &r.MatchDetails)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// storedPaths 返回数据库中有检测结果的文件名，按路径排序
+func storedPaths(t *testing.T, sqlitePath string) []string {
+	t.Helper()
+	results, err := LoadResults(sqlitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, filepath.Base(r.FilePath))
+	}
+	return names
+}
+
+func TestExportToSQLiteClearsOnlyCleanFiles(t *testing.T) {
+	dir := t.TempDir()
+	sqlitePath := filepath.Join(dir, "output.db")
+	files := []FileInfo{
+		{Path: writeFile(t, dir, "broken.zip", "")},
+		{Path: writeFile(t, dir, "clean.txt", "")},
+		{Path: writeFile(t, dir, "gone.txt", "")},
+		{Path: writeFile(t, dir, "kept.txt", "")},
+	}
+	p := NewFileProcessor()
+	scan := func(files []FileInfo) {
+		t.Helper()
+		results, scope := p.ProcessFileList(files, []string{dir})
+		if err := ExportToSQLite(sqlitePath, results, scope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeZip(t, dir, "broken.zip", "a.txt", "电话13812345678")
+	for _, name := range []string{"clean.txt", "gone.txt", "kept.txt"} {
+		writeFile(t, dir, name, "电话13812345678")
+	}
+	scan(files)
+	if got, want := storedPaths(t, sqlitePath), []string{"broken.zip", "clean.txt", "gone.txt", "kept.txt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("第一次扫描后数据库中的文件为 %q，期望 %q", got, want)
+	}
+
+	// broken.zip 损坏导致扫描失败，clean.txt 不再敏感，gone.txt 已不在文件列表中
+	writeFile(t, dir, "broken.zip", "PK\x03\x04损坏的压缩包")
+	writeFile(t, dir, "clean.txt", "没有敏感信息")
+	scan([]FileInfo{files[0], files[1], files[3]})
+	if got, want := storedPaths(t, sqlitePath), []string{"broken.zip", "kept.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("第二次扫描后数据库中的文件为 %q，期望 %q", got, want)
+	}
+}
+
+func TestScanScopeLimitedToRoots(t *testing.T) {
+	dir := t.TempDir()
+	sqlitePath := filepath.Join(dir, "output.db")
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "ab")
+	for _, d := range []string{a, b} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aFile := writeFile(t, a, "a.txt", "电话13812345678")
+	aGone := writeFile(t, a, "gone.txt", "电话13812345678")
+	bFile := writeFile(t, b, "b.txt", "电话13812345678")
+
+	state, err := OpenScanState(sqlitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+	p := NewFileProcessor()
+	p.SetScanState(state)
+	scan := func(roots []string) {
+		t.Helper()
+		files, err := WalkFiles(roots, WalkOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, scope := p.ProcessFileList(files, roots)
+		if err := ExportToSQLite(sqlitePath, results, scope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan([]string{a, b})
+	// 只扫描子目录 a 时，ab 下的记录和扫描状态不受影响，a 下已删除的文件被清理
+	if err := os.Remove(aGone); err != nil {
+		t.Fatal(err)
+	}
+	scan([]string{a})
+	if got, want := storedPaths(t, sqlitePath), []string{"a.txt", "b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("只扫描 a 后数据库中的文件为 %q，期望 %q", got, want)
+	}
+	for path, want := range map[string]bool{aFile: true, aGone: false, bFile: true} {
+		if _, ok := state.get(path); ok != want {
+			t.Errorf("%s 的扫描状态存在: %v，期望 %v", path, ok, want)
+		}
+	}
+}
+
+func TestResultWriterRescanInSameSecond(t *testing.T) {
+	db, err := openDatabase(filepath.Join(t.TempDir(), "output.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	result := func(value string, cached bool) SensitiveInfo {
+		return SensitiveInfo{
+			FileName:            "a.txt",
+			FilePath:            "/data/a.txt",
+			DetectTime:          "2024-01-01 00:00:00",
+			MatchDetails:        map[string][]Match{"phone": {{Value: value}}},
+			TotalSensitiveCount: 1,
+			cached:              cached,
+		}
+	}
+	tests := []struct {
+		name       string
+		result     SensitiveInfo
+		wantValue  string
+		wantEvents int
+	}{
+		{"首次扫描", result("138****5678", false), "138****5678", 1},
+		// 同一秒内重新扫描，检测时间相同但结果不同
+		{"同一秒内重新扫描", result("136****5678", false), "136****5678", 2},
+		{"复用缓存", result("136****5678", true), "136****5678", 2},
+	}
+	for _, tt := range tests {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := newResultWriter(tx, SourceFullScan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(tt.result); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		var value string
+		var events int
+		if err := db.QueryRow("SELECT value FROM matches").Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.QueryRow("SELECT COUNT(*) FROM detection_events").Scan(&events); err != nil {
+			t.Fatal(err)
+		}
+		if value != tt.wantValue || events != tt.wantEvents {
+			t.Errorf("%s: 命中明细为 %s，事件 %d 条，期望 %s、%d 条", tt.name, value, events, tt.wantValue, tt.wantEvents)
+		}
+	}
+}
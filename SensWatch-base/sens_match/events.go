@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 检测事件类型
+const (
+	EventSensitive = "sensitive" // 文件开始包含敏感信息
+	EventScan      = "scan"      // 敏感文件被重新扫描
+	EventCleared   = "cleared"   // 文件不再包含敏感信息，或已不在扫描范围内
+	EventMove      = "move"      // 敏感文件被移动或重命名
+	EventDelete    = "delete"    // 敏感文件被删除
+)
+
+// 事件来源
+const (
+	SourceFullScan = "full_scan"
+	SourceRealtime = "realtime"
+)
+
+// detectionEvent 表示 detection_events 表中的一条审计记录
+type detectionEvent struct {
+	Type                string
+	FilePath            string
+	OldPath             string
+	MD5                 string
+	TotalSensitiveCount int
+	RuleNumbers         string
+	MatchCounts         string
+	Source              string
+}
+
+// recordEvent 向 detection_events 表追加一条记录
+func recordEvent(tx *sql.Tx, event detectionEvent) error {
+	_, err := tx.Exec(`
+	INSERT INTO detection_events
+	(event_time, event_type, file_path, old_path, md5, total_sensitive_count, rule_numbers, match_counts, source)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		time.Now().Format("2006-01-02 15:04:05"),
+		event.Type,
+		event.FilePath,
+		nullString(event.OldPath),
+		nullString(event.MD5),
+		event.TotalSensitiveCount,
+		nullString(event.RuleNumbers),
+		nullString(event.MatchCounts),
+		event.Source)
+	if err != nil {
+		return fmt.Errorf("记录检测事件失败: %v", err)
+	}
+	return nil
+}
+
+// recordResultEvent 记录一次扫描结果：新出现的敏感文件记为 sensitive，
+// 数据库中已有记录（existed 为 true）的敏感文件记为 scan
+func recordResultEvent(tx *sql.Tx, existed bool, info SensitiveInfo, source string) error {
+	eventType := EventSensitive
+	if existed {
+		eventType = EventScan
+	}
+
+	matchCountsJSON, err := json.Marshal(info.MatchCounts)
+	if err != nil {
+		return fmt.Errorf("转换match_counts为JSON失败: %v", err)
+	}
+	return recordEvent(tx, detectionEvent{
+		Type:                eventType,
+		FilePath:            info.FilePath,
+		MD5:                 info.MD5,
+		TotalSensitiveCount: info.TotalSensitiveCount,
+		RuleNumbers:         info.RuleNumbers,
+		MatchCounts:         string(matchCountsJSON),
+		Source:              source,
+	})
+}
+
+// recordRemovalEvent 敏感文件的记录被删除前调用，记录 cleared、delete 或 move 事件。
+// 文件没有记录时说明它本来就不敏感，不记录
+func recordRemovalEvent(tx *sql.Tx, eventType, filePath, newPath, source string) error {
+	prev, ok, err := currentResult(tx, filePath)
+	if err != nil || !ok {
+		return err
+	}
+	event := detectionEvent{
+		Type:                eventType,
+		FilePath:            filePath,
+		MD5:                 prev.MD5,
+		TotalSensitiveCount: prev.TotalSensitiveCount,
+		RuleNumbers:         prev.RuleNumbers,
+		Source:              source,
+	}
+	if eventType == EventMove {
+		event.FilePath = newPath
+		event.OldPath = filePath
+	}
+	return recordEvent(tx, event)
+}
+
+// recordDirRemovalEvents 目录被删除或移出时，为其下每个敏感文件记录 delete 事件
+func recordDirRemovalEvents(tx *sql.Tx, prefix string) error {
+	rows, err := tx.Query("SELECT file_path FROM detection_results WHERE substr(file_path, 1, length(?)) = ?", prefix, prefix)
+	if err != nil {
+		return fmt.Errorf("查询数据库失败: %v", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			rows.Close()
+			return fmt.Errorf("查询数据库失败: %v", err)
+		}
+		paths = append(paths, filePath)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询数据库失败: %v", err)
+	}
+
+	for _, filePath := range paths {
+		if err := recordRemovalEvent(tx, EventDelete, filePath, "", SourceRealtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentResult 查询文件当前的检测结果。detect_time 转换为文本读取，
+// 避免驱动把 DATETIME 列解析成另一种格式的时间
+func currentResult(tx *sql.Tx, filePath string) (SensitiveInfo, bool, error) {
+	var info SensitiveInfo
+	var ruleNumbers sql.NullString
+	err := tx.QueryRow(
+		"SELECT md5, CAST(detect_time AS TEXT), total_sensitive_count, rule_numbers FROM detection_results WHERE file_path = ?",
+		filePath).Scan(&info.MD5, &info.DetectTime, &info.TotalSensitiveCount, &ruleNumbers)
+	if err == sql.ErrNoRows {
+		return info, false, nil
+	}
+	if err != nil {
+		return info, false, fmt.Errorf("查询数据库失败: %v", err)
+	}
+	info.RuleNumbers = ruleNumbers.String
+	return info, true, nil
+}
+
+// nullString 空字符串写入数据库时存为 NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
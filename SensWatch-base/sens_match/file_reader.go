@@ -2,15 +2,20 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
+	"github.com/ledongthuc/pdf"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -49,90 +54,191 @@ func readDocx(path string) (io.Reader, error) {
 		return nil, fmt.Errorf("不是有效的docx文件: %s", path)
 	}
 
-	// 查找document.xml文件
-	var content strings.Builder
+	// 依次读取正文、页眉、页脚、脚注、尾注和批注，每段文本标记所属的部件
+	var files []*zip.File
 	for _, file := range reader.File {
-		if file.Name == "word/document.xml" {
-			rc, err := file.Open()
-			if err != nil {
-				return nil, fmt.Errorf("打开document.xml失败: %v", err)
-			}
-			defer rc.Close()
+		if _, ok := docxPartName(file.Name); ok {
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return docxPartOrder(files[i].Name) < docxPartOrder(files[j].Name)
+	})
 
-			// 读取XML内容
-			data, err := io.ReadAll(rc)
-			if err != nil {
-				return nil, fmt.Errorf("读取document.xml失败: %v", err)
-			}
+	var content segmentBuilder
+	for _, file := range files {
+		name, _ := docxPartName(file.Name)
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("打开%s失败: %v", file.Name, err)
+		}
+		p := &docxParser{content: &content, part: name}
+		err = p.parse(xml.NewDecoder(rc))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析%s失败: %v", file.Name, err)
+		}
+	}
 
-			// 使用更底层的XML解析
-			decoder := xml.NewDecoder(bytes.NewReader(data))
-			for {
-				token, err := decoder.Token()
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return nil, fmt.Errorf("解析XML失败: %v", err)
-				}
+	return content.Reader(), nil
+}
 
-				switch t := token.(type) {
-				case xml.StartElement:
-					if t.Name.Local == "t" {
-						var text string
-						if err := decoder.DecodeElement(&text, &t); err != nil {
-							return nil, fmt.Errorf("解析文本节点失败: %v", err)
-						}
-						content.WriteString(text)
-					}
-				}
-			}
-			break
+// docxParts 需要提取文本的 docx 部件的文件名前缀及其在命中位置中的名称，按读取顺序排列
+var docxParts = []struct {
+	prefix, name string
+}{
+	{"word/document", "正文"},
+	{"word/header", "页眉"},
+	{"word/footer", "页脚"},
+	{"word/footnotes", "脚注"},
+	{"word/endnotes", "尾注"},
+	{"word/comments", "批注"},
+}
+
+// docxPartName 返回部件在命中位置中的名称，如 word/header1.xml 为页眉。
+// 前缀后只允许数字，排除 word/commentsExtended.xml 等不含文本的部件
+func docxPartName(fileName string) (string, bool) {
+	for _, part := range docxParts {
+		if !strings.HasPrefix(fileName, part.prefix) || !strings.HasSuffix(fileName, ".xml") {
+			continue
+		}
+		number := strings.TrimSuffix(strings.TrimPrefix(fileName, part.prefix), ".xml")
+		if strings.Trim(number, "0123456789") == "" {
+			return part.name, true
 		}
 	}
+	return "", false
+}
 
-	return strings.NewReader(content.String()), nil
+// docxPartOrder 返回部件在 docxParts 中的位置，用于排序
+func docxPartOrder(fileName string) int {
+	for i, part := range docxParts {
+		if strings.HasPrefix(fileName, part.prefix) {
+			return i
+		}
+	}
+	return len(docxParts)
 }
 
-// readPdf 读取pdf文件内容
-func readPdf(path string) (io.Reader, error) {
-	// 创建临时文件用于存储Python脚本的输出
-	tempFile := path + ".txt"
-	defer os.Remove(tempFile)
+// docxParser 流式解析 docx 部件的 XML，段落之间换行，文本框和修订中删除的文本单独标记
+type docxParser struct {
+	content *segmentBuilder
+	part    string // 当前部件的名称
+	marked  string // 最近一次标记的名称
+	runs    int    // 当前所在的文本块（w:r）层数，制表符和换行只在文本块中有效
+	textbox int    // 当前所在的文本框层数
+	skip    int    // 当前所在的需要跳过的元素层数
+}
 
-	// 构建Python命令
-	cmd := exec.Command("python", "-c", `
-import PyPDF2
-import sys
+func (p *docxParser) parse(decoder *xml.Decoder) error {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-def extract_text_from_pdf(pdf_path):
-    text = ""
-    with open(pdf_path, 'rb') as file:
-        reader = PyPDF2.PdfReader(file)
-        for i, page in enumerate(reader.pages):
-            page_text = page.extract_text()
-            if page_text:
-                text += f"\n--- Page {i+1} ---\n{page_text}\n"
-    return text
+		switch t := token.(type) {
+		case xml.StartElement:
+			if p.skip > 0 {
+				p.skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "Fallback":
+				// mc:Fallback 是旧版本 Word 使用的 VML 文本框，与 mc:Choice 中的内容重复
+				p.skip = 1
+			case "txbxContent":
+				p.textbox++
+			case "r":
+				p.runs++
+			case "t", "delText":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return fmt.Errorf("解析文本节点失败: %v", err)
+				}
+				p.write(text, t.Name.Local == "delText")
+			case "tab":
+				if p.runs > 0 {
+					p.write("\t", false)
+				}
+			case "br", "cr":
+				if p.runs > 0 {
+					p.write("\n", false)
+				}
+			}
 
-pdf_file_path = sys.argv[1]
-extracted_text = extract_text_from_pdf(pdf_file_path)
-with open(sys.argv[2], "w", encoding="utf-8") as f:
-    f.write(extracted_text)
-`, path, tempFile)
+		case xml.EndElement:
+			if p.skip > 0 {
+				p.skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				p.write("\n", false)
+			case "txbxContent":
+				p.textbox--
+			case "r":
+				p.runs--
+			}
+		}
+	}
+}
 
-	// 执行Python脚本
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("执行Python脚本失败: %v", err)
+// write 写入文本，所属位置变化时重新标记，如从正文进入文本框或修订中删除的文本
+func (p *docxParser) write(s string, deleted bool) {
+	part := p.part
+	if p.textbox > 0 {
+		part += "文本框"
+	}
+	if deleted {
+		part += "（已删除的修订）"
+	}
+	if part != p.marked {
+		p.content.Mark(Location{Part: part})
+		p.marked = part
 	}
+	p.content.WriteString(s)
+}
 
-	// 读取生成的文本文件
-	content, err := os.ReadFile(tempFile)
+// readPdf 使用纯Go解析pdf文件，按页提取文本
+func readPdf(path string) (reader io.Reader, err error) {
+	f, r, err := pdf.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("读取PDF提取结果失败: %v", err)
+		return nil, fmt.Errorf("打开pdf文件失败: %v", err)
 	}
+	defer f.Close()
+
+	// 解析库在遇到损坏的pdf时可能panic，转换为错误返回
+	defer func() {
+		if p := recover(); p != nil {
+			reader, err = nil, fmt.Errorf("解析pdf文件失败: %v", p)
+		}
+	}()
 
-	return bytes.NewReader(content), nil
+	var content segmentBuilder
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("提取第%d页文本失败: %v", i, err)
+		}
+
+		content.Mark(Location{Page: i})
+		for _, row := range rows {
+			for _, word := range row.Content {
+				content.WriteString(word.S)
+			}
+			content.WriteString("\n")
+		}
+	}
+
+	return content.Reader(), nil
 }
 
 // readXlsx 读取xlsx文件内容
@@ -143,21 +249,25 @@ func readXlsx(path string) (io.Reader, error) {
 	}
 	defer f.Close()
 
-	var content strings.Builder
+	var content segmentBuilder
 	for _, sheet := range f.GetSheetList() {
 		rows, err := f.GetRows(sheet)
 		if err != nil {
 			return nil, fmt.Errorf("读取xlsx工作表失败: %v", err)
 		}
-		for _, row := range rows {
-			for _, cell := range row {
+		for r, row := range rows {
+			for c, cell := range row {
+				if cell != "" {
+					cellName, _ := excelize.CoordinatesToCellName(c+1, r+1)
+					content.Mark(Location{Sheet: sheet, Cell: cellName})
+				}
 				content.WriteString(cell)
 				content.WriteString("\t")
 			}
 			content.WriteString("\n")
 		}
 	}
-	return strings.NewReader(content.String()), nil
+	return content.Reader(), nil
 }
 
 // readPptx 读取pptx文件内容
@@ -169,10 +279,13 @@ func readPptx(path string) (io.Reader, error) {
 	}
 	defer reader.Close()
 
-	var content strings.Builder
+	var content segmentBuilder
 	// 遍历所有幻灯片
 	for _, file := range reader.File {
 		if strings.HasPrefix(file.Name, "ppt/slides/slide") && strings.HasSuffix(file.Name, ".xml") {
+			slide, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(file.Name, "ppt/slides/slide"), ".xml"))
+			content.Mark(Location{Slide: slide})
+
 			rc, err := file.Open()
 			if err != nil {
 				return nil, fmt.Errorf("打开幻灯片文件失败: %v", err)
@@ -212,21 +325,121 @@ func readPptx(path string) (io.Reader, error) {
 		}
 	}
 
-	return strings.NewReader(content.String()), nil
+	return content.Reader(), nil
 }
 
-// GetFileReader 根据文件扩展名获取文件读取器
+// readUTF16 读取 UTF-16 编码的文本文件，转换为 UTF-8
+func readUTF16(path string, order binary.ByteOrder) (io.Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	return &utf16Reader{file: file, src: bufio.NewReader(file), order: order, first: true}, nil
+}
+
+// utf16Reader 把 UTF-16 字节流逐个字符转换为 UTF-8，开头的 BOM 被丢弃
+type utf16Reader struct {
+	file    *os.File
+	src     *bufio.Reader
+	order   binary.ByteOrder
+	first   bool
+	pending []byte // 已转换但还没有读走的字节
+}
+
+func (r *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			c, err := r.readRune()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+			r.pending = utf8.AppendRune(r.pending[:0], c)
+		}
+		copied := copy(p[n:], r.pending)
+		r.pending = r.pending[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// readRune 读取一个 UTF-16 字符，代理对合并为一个字符
+func (r *utf16Reader) readRune() (rune, error) {
+	var unit [2]byte
+	for {
+		if _, err := io.ReadFull(r.src, unit[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		c := rune(r.order.Uint16(unit[:]))
+		if r.first {
+			r.first = false
+			if c == 0xFEFF {
+				continue
+			}
+		}
+		if !utf16.IsSurrogate(c) {
+			return c, nil
+		}
+		if _, err := io.ReadFull(r.src, unit[:]); err != nil {
+			return utf8.RuneError, nil
+		}
+		return utf16.DecodeRune(c, rune(r.order.Uint16(unit[:]))), nil
+	}
+}
+
+func (r *utf16Reader) Close() error {
+	return r.file.Close()
+}
+
+// GetFileReader 根据文件内容识别出的类型获取文件读取器，不依赖扩展名。
+// 图片、可执行文件等无法读取的类型返回 *unsupportedTypeError
 func GetFileReader(path string) (io.Reader, error) {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".docx":
+	ctype, err := sniffFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !ctype.Scannable() {
+		return nil, &unsupportedTypeError{path: path, ctype: ctype}
+	}
+	return readerForType(path, ctype, defaultArchiveLimits)
+}
+
+// readerForType 根据内容类型选择读取器，其它类型按普通文本读取。
+// limits 为展开文件内部的压缩包（如邮件中的压缩包附件）时的限制
+func readerForType(path string, ctype ContentType, limits ArchiveLimits) (io.Reader, error) {
+	switch ctype {
+	case TypeDocx:
 		return readDocx(path)
-	case ".pdf":
+	case TypePDF:
 		return readPdf(path)
-	case ".xlsx":
+	case TypeXlsx:
 		return readXlsx(path)
-	case ".pptx":
+	case TypePptx:
 		return readPptx(path)
+	case TypeDoc:
+		return readDoc(path)
+	case TypeXls:
+		return readXls(path)
+	case TypePpt:
+		return readPpt(path)
+	case TypeOdt, TypeOds, TypeOdp:
+		return readOdf(path, ctype)
+	case TypeEml:
+		return readEml(path, limits)
+	case TypeMbox:
+		return readMbox(path, limits)
+	case TypeUTF16LE:
+		return readUTF16(path, binary.LittleEndian)
+	case TypeUTF16BE:
+		return readUTF16(path, binary.BigEndian)
+	case TypeEmpty:
+		return strings.NewReader(""), nil
 	default:
 		// 对于其他文件类型，直接返回文件句柄
 		file, err := os.Open(path)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// wordXML 生成带 w 命名空间声明的 docx 部件
+func wordXML(root, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:` + root + ` xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+ xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"
+ xmlns:wps="http://schemas.microsoft.com/office/word/2010/wordprocessingShape">` + body + `</w:` + root + `>`
+}
+
+func TestReadDocx(t *testing.T) {
+	path := writeZip(t, t.TempDir(), "a.docx",
+		"word/comments.xml", wordXML("comments", `<w:comment><w:p><w:r><w:t>批注邮箱a@example.com</w:t></w:r></w:p></w:comment>`),
+		"word/commentsExtended.xml", wordXML("commentsEx", `<w:t>不应读取13000000000</w:t>`),
+		"word/header1.xml", wordXML("hdr", `<w:p><w:r><w:t>页眉电话13912345678</w:t></w:r></w:p>`),
+		"word/footnotes.xml", wordXML("footnotes", `<w:footnote><w:p><w:r><w:t>脚注13712345678</w:t></w:r></w:p></w:footnote>`),
+		"word/document.xml", wordXML("document", `<w:body>
+<w:p><w:r><w:t>姓名</w:t><w:tab/><w:t>张三</w:t><w:br/><w:t>电话13812345678</w:t></w:r></w:p>
+<w:p><w:del><w:r><w:delText>已删除13612345678</w:delText></w:r></w:del></w:p>
+<w:p><w:r><mc:AlternateContent><mc:Choice><w:drawing><wps:txbx><w:txbxContent><w:p><w:r><w:t>文本框13512345678</w:t></w:r></w:p></w:txbxContent></wps:txbx></w:drawing></mc:Choice>
+<mc:Fallback><w:pict><w:txbxContent><w:p><w:r><w:t>文本框13512345678</w:t></w:r></w:p></w:txbxContent></w:pict></mc:Fallback></mc:AlternateContent></w:r></w:p>
+</w:body>`),
+	)
+
+	tests := []struct {
+		text string
+		part string
+	}{
+		{"姓名\t张三\n电话13812345678", "正文"},
+		{"已删除13612345678", "正文（已删除的修订）"},
+		{"文本框13512345678", "正文文本框"},
+		{"页眉电话13912345678", "页眉"},
+		{"脚注13712345678", "脚注"},
+		{"批注邮箱a@example.com", "批注"},
+	}
+	for _, tt := range tests {
+		r, err := readDocx(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, loc := readLocated(t, r, tt.text)
+		if loc == nil || loc.Part != tt.part {
+			t.Errorf("%s 的位置为 %v，期望 %s", tt.text, loc, tt.part)
+		}
+		if strings.Count(text, "文本框13512345678") != 1 {
+			t.Errorf("mc:Fallback 中重复的文本框不应读取: %q", text)
+		}
+		if strings.Contains(text, "13000000000") {
+			t.Errorf("不含文本的部件不应读取: %q", text)
+		}
+		// 正文在页眉、脚注和批注之前
+		if strings.Index(text, "电话13812345678") > strings.Index(text, "页眉") ||
+			strings.Index(text, "脚注") > strings.Index(text, "批注") {
+			t.Errorf("部件的读取顺序不对: %q", text)
+		}
+	}
+}
+
+func TestReadDocxInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"空文件", writeFile(t, dir, "empty.docx", "")},
+		{"不是 zip", writeFile(t, dir, "text.docx", "纯文本")},
+		{"缺少 document.xml", writeZip(t, dir, "nodoc.docx", "word/header1.xml", wordXML("hdr", ""))},
+		{"XML 损坏", writeZip(t, dir, "broken.docx", "word/document.xml", `<w:document><w:body>`)},
+	}
+	for _, tt := range tests {
+		if _, err := readDocx(tt.path); err == nil {
+			t.Errorf("%s: 应当返回错误", tt.name)
+		}
+	}
+}
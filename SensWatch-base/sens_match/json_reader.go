@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
 )
 
 // ReadFileList 从JSON文件读取文件列表
@@ -19,11 +20,35 @@ func ReadFileList(filePath string) ([]FileInfo, error) {
 		return nil, fmt.Errorf("解析JSON失败: %v", err)
 	}
 
-	// 将map转换为slice
+	// 将map转换为slice，按路径排序保证每次扫描顺序一致
 	var fileList []FileInfo
 	for _, info := range fileDict.FileDict {
 		fileList = append(fileList, info)
 	}
+	sort.Slice(fileList, func(i, j int) bool {
+		return fileList[i].Path < fileList[j].Path
+	})
 
 	return fileList, nil
 }
+
+// ReadWatchRoots 从 read_path.py 写入的目录记录中读取已建立索引的目录
+func ReadWatchRoots(filePath string) ([]string, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录记录失败: %v", err)
+	}
+
+	// 目录记录的格式为 {目录: 最新时间戳}
+	var directoryLog map[string]float64
+	if err := json.Unmarshal(data, &directoryLog); err != nil {
+		return nil, fmt.Errorf("解析目录记录失败: %v", err)
+	}
+
+	var roots []string
+	for dir := range directoryLog {
+		roots = append(roots, dir)
+	}
+	sort.Strings(roots)
+	return roots, nil
+}
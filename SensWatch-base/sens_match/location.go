@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Location 表示命中在原始文档中的位置，读取器不知道的字段留空
+type Location struct {
+	Page  int    `json:"page,omitempty"`  // PDF 页码
+	Sheet string `json:"sheet,omitempty"` // 工作表名称
+	Cell  string `json:"cell,omitempty"`  // 单元格，如 B2
+	Slide int    `json:"slide,omitempty"` // 幻灯片编号
+
+	Member     string `json:"member,omitempty"`     // 压缩包成员的虚拟路径，如 backup.zip!/hr/staff.xlsx
+	MessageID  string `json:"message_id,omitempty"` // 邮件的 Message-ID
+	Attachment string `json:"attachment,omitempty"` // 邮件附件的文件名
+	Part       string `json:"part,omitempty"`       // 文档中的部分，如 docx 的页眉、批注、已删除的修订
+}
+
+// String 返回便于阅读的位置描述，如 "第3页"、"工作表 Sheet1 单元格 B2"、
+// "backup.zip!/hr/staff.xlsx 工作表 Sheet1 单元格 B2"
+func (l *Location) String() string {
+	var parts []string
+	if l.Member != "" {
+		parts = append(parts, l.Member)
+	}
+	if l.MessageID != "" {
+		parts = append(parts, "邮件 "+l.MessageID)
+	}
+	if l.Attachment != "" {
+		parts = append(parts, "附件 "+l.Attachment)
+	}
+	if l.Part != "" {
+		parts = append(parts, l.Part)
+	}
+	if l.Page > 0 {
+		parts = append(parts, fmt.Sprintf("第%d页", l.Page))
+	}
+	if l.Sheet != "" {
+		parts = append(parts, "工作表 "+l.Sheet)
+	}
+	if l.Cell != "" {
+		parts = append(parts, "单元格 "+l.Cell)
+	}
+	if l.Slide > 0 {
+		parts = append(parts, fmt.Sprintf("第%d张幻灯片", l.Slide))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Locator 由能够把提取文本中的偏移映射回文档位置的读取器实现
+type Locator interface {
+	Locate(offset int64) *Location
+}
+
+// segment 表示提取文本中从 offset 开始的一段对应的文档位置
+type segment struct {
+	offset int64
+	loc    Location
+}
+
+// mismatchReporter 由能够发现内部文件扩展名与内容不符的读取器实现，如邮件附件
+type mismatchReporter interface {
+	Mismatches() []Match
+}
+
+// segmentedReader 由读完后能给出全部分段位置的读取器实现，嵌入其它文档时用于保留内层位置
+type segmentedReader interface {
+	mismatchReporter
+	Segments() []segment
+}
+
+// locate 返回 segments 中包含 offset 的分段位置，segments 按 offset 升序排列
+func locate(segments []segment, offset int64) *Location {
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].offset > offset
+	}) - 1
+	if i < 0 || segments[i].loc == (Location{}) {
+		return nil
+	}
+	loc := segments[i].loc
+	return &loc
+}
+
+// nestLocation 返回外层位置 outer 中的内层位置 inner，如压缩包中邮件的附件中的单元格。
+// 压缩包成员的虚拟路径和附件名逐层拼接，Message-ID 使用最外层的邮件
+func nestLocation(outer, inner Location) Location {
+	loc := inner
+	if outer.Member != "" {
+		loc.Member = outer.Member
+		if inner.Member != "" {
+			loc.Member += archiveSeparator + inner.Member
+		}
+	}
+	if outer.MessageID != "" {
+		loc.MessageID = outer.MessageID
+	}
+	if outer.Attachment != "" {
+		loc.Attachment = outer.Attachment
+		if inner.Attachment != "" {
+			loc.Attachment += "/" + inner.Attachment
+		}
+	}
+	return loc
+}
+
+// segmentBuilder 在拼接提取文本的同时记录每一段的文档位置
+type segmentBuilder struct {
+	strings.Builder
+	segments   []segment
+	mismatches []Match
+}
+
+// Mark 标记接下来写入的文本属于 loc
+func (b *segmentBuilder) Mark(loc Location) {
+	b.segments = append(b.segments, segment{offset: int64(b.Len()), loc: loc})
+}
+
+// Mismatch 记录一个扩展名与内容不符的内部文件，m.Location 为其位置
+func (b *segmentBuilder) Mismatch(m Match) {
+	b.mismatches = append(b.mismatches, m)
+}
+
+// Append 追加 r 中的全部文本并标记为 base。r 带位置信息时保留其中的分段，
+// 用 base 补上邮件、附件等外层位置，如邮件附件中的工作表和单元格；
+// r 中扩展名与内容不符的内部文件同样补上外层位置
+func (b *segmentBuilder) Append(r io.Reader, base Location) error {
+	start := int64(b.Len())
+	b.Mark(base)
+	if _, err := io.Copy(&b.Builder, r); err != nil {
+		return err
+	}
+	sr, ok := r.(segmentedReader)
+	if !ok {
+		return nil
+	}
+	for _, s := range sr.Segments() {
+		b.segments = append(b.segments, segment{offset: start + s.offset, loc: nestLocation(base, s.loc)})
+	}
+	for _, m := range sr.Mismatches() {
+		var inner Location
+		if m.Location != nil {
+			inner = *m.Location
+		}
+		loc := nestLocation(base, inner)
+		m.Location = &loc
+		b.mismatches = append(b.mismatches, m)
+	}
+	return nil
+}
+
+// Reader 返回带位置信息的读取器
+func (b *segmentBuilder) Reader() io.Reader {
+	return &segmentReader{
+		Reader:     strings.NewReader(b.String()),
+		segments:   b.segments,
+		mismatches: b.mismatches,
+	}
+}
+
+// segmentReader 实现 Locator 和 mismatchReporter 的文本读取器
+type segmentReader struct {
+	*strings.Reader
+	segments   []segment
+	mismatches []Match
+}
+
+// Mismatches 返回扩展名与内容不符的内部文件
+func (r *segmentReader) Mismatches() []Match {
+	return r.mismatches
+}
+
+// Segments 返回所有分段位置
+func (r *segmentReader) Segments() []segment {
+	return r.segments
+}
+
+// Locate 返回包含 offset 的分段位置
+func (r *segmentReader) Locate(offset int64) *Location {
+	return locate(r.segments, offset)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// mailHeadersToScan 需要检测的邮件头，Received 等传输相关的头包含大量 IP 和主机名，不检测
+var mailHeadersToScan = []string{"From", "Sender", "Reply-To", "To", "Cc", "Bcc", "Subject"}
+
+// maxMailDepth 邮件中嵌套转发的邮件（message/rfc822）最多展开的层数
+const maxMailDepth = 5
+
+// mailWordDecoder 解码 RFC 2047 编码的邮件头，如 =?GB2312?B?...?=
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader 把指定字符集的内容转换为 UTF-8，支持 GBK、GB18030、Big5 等常见字符集
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的字符集: %s", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// readEml 读取 .eml 邮件，解码邮件头、各种字符集和传输编码的正文以及附件。
+// 命中位置记录邮件的 Message-ID 和附件文件名，limits 为展开压缩包附件时的限制
+func readEml(path string, limits ArchiveLimits) (io.Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开邮件文件失败: %v", err)
+	}
+	defer file.Close()
+
+	r := &mailReader{limits: limits}
+	if err := r.message(bufio.NewReader(file), Location{}, 0); err != nil {
+		return nil, fmt.Errorf("解析邮件失败: %v", err)
+	}
+	return r.content.Reader(), nil
+}
+
+// readMbox 读取 mbox 邮箱文件，以 "From " 开头的行分隔各封邮件，
+// 正文中转义为 ">From " 的行还原后再解析。邮件逐封读取，内存中只保留当前一封的内容；
+// 无法解析的邮件打印其序号和 Message-ID 后跳过，不影响其它邮件
+func readMbox(path string, limits ArchiveLimits) (io.Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开邮箱文件失败: %v", err)
+	}
+	return &mboxReader{
+		path:      path,
+		file:      file,
+		br:        bufio.NewReader(file),
+		mail:      &mailReader{limits: limits},
+		current:   strings.NewReader(""),
+		prevBlank: true,
+	}, nil
+}
+
+// mboxReader 逐封解析 mbox 中的邮件并输出其文本，实现 Locator 和 mismatchReporter。
+// 各封邮件共用同一个 mailReader，压缩包附件的限制对整个邮箱生效
+type mboxReader struct {
+	path      string
+	file      *os.File
+	br        *bufio.Reader
+	mail      *mailReader
+	current   *strings.Reader // 当前邮件的文本
+	base      int64           // 当前邮件的文本在整个邮箱文本中的偏移
+	count     int             // 已读取的邮件数
+	eof       bool
+	prevBlank bool
+
+	segments   []segment
+	mismatches []Match
+}
+
+// Read 输出当前邮件的文本，读完后解析下一封
+func (m *mboxReader) Read(p []byte) (int, error) {
+	for m.current.Len() == 0 {
+		data, err := m.nextMessage()
+		if err != nil {
+			return 0, err
+		}
+		m.load(data)
+	}
+	return m.current.Read(p)
+}
+
+// nextMessage 读取下一封邮件的原始内容，没有更多邮件时返回 io.EOF
+func (m *mboxReader) nextMessage() ([]byte, error) {
+	var msg bytes.Buffer
+	for !m.eof {
+		line, err := m.br.ReadString('\n')
+		if err == io.EOF {
+			m.eof = true
+		} else if err != nil {
+			return nil, fmt.Errorf("读取邮箱文件失败: %v", err)
+		}
+		if line == "" {
+			continue
+		}
+		prevBlank := m.prevBlank
+		m.prevBlank = strings.TrimRight(line, "\r\n") == ""
+		switch {
+		case prevBlank && strings.HasPrefix(line, "From "):
+			if msg.Len() > 0 {
+				return msg.Bytes(), nil
+			}
+		case strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") && line[0] == '>':
+			msg.WriteString(line[1:])
+		default:
+			msg.WriteString(line)
+		}
+	}
+	if msg.Len() == 0 {
+		return nil, io.EOF
+	}
+	return msg.Bytes(), nil
+}
+
+// load 解析一封邮件作为当前内容，分段位置换算为在整个邮箱文本中的偏移。
+// 解析失败时保留出错前已经读出的内容
+func (m *mboxReader) load(data []byte) {
+	m.count++
+	m.base += m.current.Size()
+	m.mail.content = segmentBuilder{}
+	if err := m.mail.message(bytes.NewReader(data), Location{}, 0); err != nil {
+		fmt.Printf("跳过邮箱 %s 中的第 %d 封邮件%s: %v\n", m.path, m.count, rawMessageID(data), err)
+	}
+	for _, s := range m.mail.content.segments {
+		m.segments = append(m.segments, segment{offset: m.base + s.offset, loc: s.loc})
+	}
+	m.mismatches = append(m.mismatches, m.mail.content.mismatches...)
+	m.current = strings.NewReader(m.mail.content.String())
+}
+
+// Segments 返回已读取的邮件的分段位置
+func (m *mboxReader) Segments() []segment {
+	return m.segments
+}
+
+// Mismatches 返回已读取的邮件中扩展名与内容不符的附件
+func (m *mboxReader) Mismatches() []Match {
+	return m.mismatches
+}
+
+// Locate 返回包含 offset 的分段位置
+func (m *mboxReader) Locate(offset int64) *Location {
+	return locate(m.segments, offset)
+}
+
+// Close 关闭邮箱文件
+func (m *mboxReader) Close() error {
+	return m.file.Close()
+}
+
+// rawMessageID 从无法解析的邮件中尽量取出 Message-ID，用于日志，如 "（<a@example.com>）"
+func rawMessageID(data []byte) string {
+	header, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if id := strings.TrimSpace(header.Get("Message-Id")); id != "" {
+		return "（" + id + "）"
+	}
+	return ""
+}
+
+// mailReader 把邮件的各部分拼接为文本，并记录每段文本属于哪封邮件、哪个附件
+type mailReader struct {
+	content segmentBuilder
+	limits  ArchiveLimits
+	archive *archiveWalker // 展开压缩包附件，同一邮件文件中的所有压缩包附件共用限制
+}
+
+// message 解析一封邮件。parent 为外层位置，转发的邮件沿用外层邮件的 Message-ID
+func (r *mailReader) message(data io.Reader, parent Location, depth int) error {
+	msg, err := mail.ReadMessage(data)
+	if err != nil {
+		return err
+	}
+
+	loc := parent
+	if parent.MessageID == "" {
+		loc.MessageID = strings.TrimSpace(msg.Header.Get("Message-Id"))
+	}
+	r.content.Mark(loc)
+	for _, name := range mailHeadersToScan {
+		for _, value := range msg.Header[name] {
+			r.content.WriteString(name + ": " + decodeMailHeader(value) + "\n")
+		}
+	}
+	r.content.WriteString("\n")
+
+	return r.entity(textproto.MIMEHeader(msg.Header), msg.Body, loc, depth)
+}
+
+// entity 处理邮件或 multipart 中的一个部分：multipart 逐个处理子部分，正文按字符集解码，
+// 附件按内容识别类型后读取
+func (r *mailReader) entity(header textproto.MIMEHeader, body io.Reader, loc Location, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	body = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	name := mailAttachmentName(header, params)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("解析multipart失败: %v", err)
+			}
+			if err := r.entity(part.Header, part, loc, depth); err != nil {
+				return err
+			}
+		}
+
+	case mediaType == "message/rfc822":
+		if depth >= maxMailDepth {
+			return nil
+		}
+		if name == "" {
+			name = "message.eml"
+		}
+		return r.message(body, childLocation(loc, name), depth+1)
+
+	case name == "" && strings.HasPrefix(mediaType, "text/"):
+		r.content.Mark(loc)
+		if err := r.text(body, params["charset"]); err != nil {
+			return err
+		}
+		r.content.WriteString("\n")
+		return nil
+
+	default:
+		if name == "" {
+			name = "未命名附件（" + mediaType + "）"
+		}
+		return r.attachment(body, name, childLocation(loc, name))
+	}
+}
+
+// text 按字符集解码正文后写入，字符集不支持时按原样写入
+func (r *mailReader) text(body io.Reader, charset string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("读取邮件正文失败: %v", err)
+	}
+	if decoder, err := charsetReader(charset, bytes.NewReader(data)); err == nil {
+		if decoded, err := io.ReadAll(decoder); err == nil {
+			data = decoded
+		}
+	}
+	r.content.Write(data)
+	return nil
+}
+
+// attachment 把附件写入私有临时目录中的临时文件，按内容识别类型后读取，name 为附件的文件名。
+// 扩展名与内容不符的附件单独记录；压缩包附件逐个读取其中的成员，位置记录成员的虚拟路径，
+// 如 附件 backup.zip 中的 backup.zip!/hr/staff.xlsx；不支持的类型（如图片）跳过
+func (r *mailReader) attachment(body io.Reader, name string, loc Location) error {
+	tmp, err := createScratchFile("mail_*" + filepath.Ext(name))
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, body)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("解码附件 %s 失败: %v", loc.Attachment, err)
+	}
+
+	ctype, err := sniffFile(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("识别附件 %s 的类型失败: %v", loc.Attachment, err)
+	}
+	r.checkExtension(name, ctype, loc)
+	switch {
+	case isArchive(ctype) && r.limits.MaxDepth > 0:
+		return r.walkArchive(tmp.Name(), ctype, name, loc)
+	case ctype.Scannable():
+		return r.appendFile(tmp.Name(), ctype, loc, r.limits)
+	}
+	return nil
+}
+
+// walkArchive 展开压缩包附件，逐个读取其中的成员
+func (r *mailReader) walkArchive(path string, ctype ContentType, name string, loc Location) error {
+	if r.archive == nil {
+		r.archive = &archiveWalker{limits: r.limits}
+	}
+	w := r.archive
+	w.leaf = func(tmp string, ctype ContentType, member, virtual string, depth int) error {
+		memberLoc := loc
+		memberLoc.Member = virtual
+		r.checkExtension(member, ctype, memberLoc)
+		if !ctype.Scannable() {
+			return nil
+		}
+		return r.appendFile(tmp, ctype, memberLoc, w.nestedLimits(depth))
+	}
+	if err := w.walkArchive(path, ctype, name, 1); err != nil {
+		return fmt.Errorf("展开附件 %s 失败: %v", loc.Attachment, err)
+	}
+	return nil
+}
+
+// appendFile 按类型读取附件或压缩包附件中的成员，追加到邮件内容中
+func (r *mailReader) appendFile(path string, ctype ContentType, loc Location, limits ArchiveLimits) error {
+	reader, err := readerForType(path, ctype, limits)
+	if err != nil {
+		return fmt.Errorf("读取附件 %s 失败: %v", loc.Attachment, err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if err := r.content.Append(reader, loc); err != nil {
+		return fmt.Errorf("读取附件 %s 失败: %v", loc.Attachment, err)
+	}
+	r.content.WriteString("\n")
+	return nil
+}
+
+// checkExtension 附件或压缩包附件中的成员的扩展名与内容不符时记录下来
+func (r *mailReader) checkExtension(name string, ctype ContentType, loc Location) {
+	if extensionMismatch(name, ctype) {
+		m := typeMismatchMatch(name, ctype)
+		m.Location = &loc
+		r.content.Mismatch(m)
+	}
+}
+
+// childLocation 返回附件的位置，转发邮件中的附件记录为 "外层附件/附件"
+func childLocation(parent Location, name string) Location {
+	loc := parent
+	if parent.Attachment != "" {
+		name = parent.Attachment + "/" + name
+	}
+	loc.Attachment = name
+	return loc
+}
+
+// decodeTransferEncoding 解码 base64 和 quoted-printable 传输编码。
+// multipart 的子部分用 NextRawPart 读取，也在这里统一解码
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// mailAttachmentName 返回附件的文件名，依次取 Content-Disposition 的 filename 和 Content-Type 的 name。
+// 没有文件名且不是 attachment 的部分视为正文
+func mailAttachmentName(header textproto.MIMEHeader, params map[string]string) string {
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if name == "" && disposition == "attachment" {
+		name = "未命名附件"
+	}
+	return decodeMailHeader(name)
+}
+
+// decodeMailHeader 解码 RFC 2047 编码的邮件头，解码失败时返回原值
+func decodeMailHeader(value string) string {
+	decoded, err := mailWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
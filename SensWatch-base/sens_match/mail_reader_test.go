@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// mailAttachment 测试邮件中的一个附件
+type mailAttachment struct {
+	name string
+	data string
+}
+
+// buildMail 生成带附件的 multipart 邮件，附件使用 base64 编码
+func buildMail(messageID, body string, attachments ...mailAttachment) string {
+	var b strings.Builder
+	b.WriteString("From: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte("张三")) + "?= <zhangsan@example.com>\r\n")
+	b.WriteString("To: lisi@example.com\r\n")
+	b.WriteString("Subject: test\r\n")
+	b.WriteString("Message-ID: " + messageID + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n")
+	b.WriteString("--BOUNDARY\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + body + "\r\n")
+	for _, a := range attachments {
+		b.WriteString("--BOUNDARY\r\nContent-Type: application/octet-stream\r\n")
+		b.WriteString("Content-Disposition: attachment; filename=\"" + a.name + "\"\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		b.WriteString(base64.StdEncoding.EncodeToString([]byte(a.data)) + "\r\n")
+	}
+	b.WriteString("--BOUNDARY--\r\n")
+	return b.String()
+}
+
+// readFileString 读取 writeZip 等生成的文件内容，作为附件数据
+func readFileString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReadEmlCharsets(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("联系电话13812345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := simplifiedchinese.GBK.NewEncoder().String("工资表")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := "From: a@example.com\r\nTo: b@example.com\r\n" +
+		"Subject: =?GB2312?B?" + base64.StdEncoding.EncodeToString([]byte(subject)) + "?=\r\n" +
+		"Message-ID: <gbk@example.com>\r\n" +
+		"Content-Type: text/plain; charset=GBK\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(gbk)) + "\r\n"
+	path := writeFile(t, t.TempDir(), "a.eml", mail)
+
+	r, err := readEml(path, defaultArchiveLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, loc := readLocated(t, r, "联系电话13812345678")
+	if !strings.Contains(text, "Subject: 工资表") {
+		t.Errorf("邮件头没有解码: %q", text)
+	}
+	if loc == nil || loc.MessageID != "<gbk@example.com>" {
+		t.Errorf("正文的位置为 %v，期望邮件 <gbk@example.com>", loc)
+	}
+}
+
+func TestReadEmlAttachments(t *testing.T) {
+	dir := t.TempDir()
+	docx := readFileString(t, writeZip(t, dir, "合同.docx",
+		"word/document.xml", `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>身份证110101199003077777</w:t></w:r></w:p></w:body></w:document>`))
+	backup := readFileString(t, writeZip(t, dir, "backup.zip",
+		"hr/staff.txt", "员工电话13612345678",
+		"hr/id.jpg", docx,
+		".~lock.staff.txt#", "临时文件13512345678"))
+	path := writeFile(t, dir, "a.eml", buildMail("<m1@example.com>", "正文13812345678",
+		mailAttachment{"合同.docx", docx},
+		mailAttachment{"backup.zip", backup},
+		mailAttachment{"说明.txt", "MZ\x90\x00\x03\x00\x00\x00"},
+		mailAttachment{"logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"},
+	))
+
+	tests := []struct {
+		text string
+		want Location
+	}{
+		{"正文13812345678", Location{MessageID: "<m1@example.com>"}},
+		{"身份证110101199003077777", Location{MessageID: "<m1@example.com>", Attachment: "合同.docx", Part: "正文"}},
+		{"员工电话13612345678", Location{MessageID: "<m1@example.com>", Attachment: "backup.zip", Member: "backup.zip!/hr/staff.txt"}},
+	}
+	for _, tt := range tests {
+		r, err := readEml(path, defaultArchiveLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, loc := readLocated(t, r, tt.text)
+		if loc == nil || *loc != tt.want {
+			t.Errorf("%s 的位置为 %+v，期望 %+v", tt.text, loc, tt.want)
+		}
+		if strings.Contains(text, "13512345678") {
+			t.Errorf("压缩包附件中的临时文件不应读取")
+		}
+	}
+
+	r, err := readEml(path, defaultArchiveLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range r.(mismatchReporter).Mismatches() {
+		got = append(got, m.Location.String()+" "+m.Value)
+	}
+	want := []string{
+		"backup.zip!/hr/id.jpg 邮件 <m1@example.com> 附件 backup.zip 扩展名 .jpg，实际内容为 docx",
+		"邮件 <m1@example.com> 附件 说明.txt 扩展名 .txt，实际内容为 exe",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("扩展名与内容不符的附件为:\n%s\n期望:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// 不展开压缩包时压缩包附件跳过
+	r, err = readEml(path, ArchiveLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := readLocated(t, r, "正文13812345678"); strings.Contains(text, "13612345678") {
+		t.Errorf("不展开压缩包时不应读取压缩包附件中的成员")
+	}
+}
+
+func TestReadMailErrors(t *testing.T) {
+	dir := t.TempDir()
+	badAttachment := strings.Replace(buildMail("<bad@example.com>", "正文", mailAttachment{"a.txt", "内容"}),
+		base64.StdEncoding.EncodeToString([]byte("内容")), "!!!!", 1)
+	bomb := readFileString(t, writeZip(t, dir, "bomb.zip", "a.txt", strings.Repeat("0", 4096)))
+
+	tests := []struct {
+		name string
+		read func(string, ArchiveLimits) (io.Reader, error)
+		data string
+	}{
+		{"附件的 base64 无效", readEml, badAttachment},
+		{"压缩包附件超出限制", readEml, buildMail("<bomb@example.com>", "正文", mailAttachment{"bomb.zip", bomb})},
+	}
+	limits := defaultArchiveLimits
+	limits.MaxTotalBytes = 1024
+	for _, tt := range tests {
+		path := writeFile(t, dir, "mail", tt.data)
+		if _, err := tt.read(path, limits); err == nil {
+			t.Errorf("%s: 应当返回错误", tt.name)
+		}
+	}
+}
+
+func TestReadMbox(t *testing.T) {
+	mbox := "From a@example.com Mon Jan 1 00:00:00 2024\n" +
+		"From: a@example.com\nMessage-ID: <1@example.com>\n\n第一封13812345678\n>From 转义的行\n\n" +
+		"From b@example.com Mon Jan 1 00:00:00 2024\n" +
+		"From: b@example.com\nMessage-ID: <2@example.com>\n\n第二封13612345678\n"
+	path := writeFile(t, t.TempDir(), "a.mbox", mbox)
+
+	for _, tt := range []struct{ text, id string }{
+		{"第一封13812345678", "<1@example.com>"},
+		{"第二封13612345678", "<2@example.com>"},
+	} {
+		r, err := readMbox(path, defaultArchiveLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, loc := readLocated(t, r, tt.text)
+		if loc == nil || loc.MessageID != tt.id {
+			t.Errorf("%s 的位置为 %v，期望邮件 %s", tt.text, loc, tt.id)
+		}
+		if !strings.Contains(text, "\nFrom 转义的行") {
+			t.Errorf(">From 没有还原: %q", text)
+		}
+	}
+}
+
+func TestReadMboxSkipsBadMessage(t *testing.T) {
+	mbox := "From a@example.com Mon Jan 1 00:00:00 2024\n" +
+		"From: a@example.com\nMessage-ID: <1@example.com>\n\n第一封13812345678\n\n" +
+		"From b@example.com Mon Jan 1 00:00:00 2024\n" +
+		"From: b@example.com\nMessage-ID: <bad@example.com>\nbroken header\n\n无效13712345678\n\n" +
+		"From c@example.com Mon Jan 1 00:00:00 2024\n" +
+		"From: c@example.com\nMessage-ID: <3@example.com>\n\n第三封13612345678\n"
+	path := writeFile(t, t.TempDir(), "a.mbox", mbox)
+
+	for _, tt := range []struct{ text, id string }{
+		{"第一封13812345678", "<1@example.com>"},
+		{"第三封13612345678", "<3@example.com>"},
+	} {
+		r, err := readMbox(path, defaultArchiveLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, loc := readLocated(t, r, tt.text)
+		r.(io.Closer).Close()
+		if loc == nil || loc.MessageID != tt.id {
+			t.Errorf("%s 的位置为 %v，期望邮件 %s", tt.text, loc, tt.id)
+		}
+		if strings.Contains(text, "无效13712345678") {
+			t.Errorf("无法解析的邮件应当跳过: %q", text)
+		}
+	}
+}
+
+func TestProcessFileMailMismatches(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "a.eml", buildMail("<m2@example.com>", "正文", mailAttachment{"说明.txt", "MZ\x90\x00\x03\x00\x00\x00"}))
+	// 压缩包中的邮件，邮件的压缩包附件中的成员
+	inner := readFileString(t, writeZip(t, dir, "inner.zip", "a.txt", "电话13812345678"))
+	outer := writeZip(t, dir, "outer.zip", "mail/b.eml", buildMail("<m3@example.com>", "正文", mailAttachment{"inner.zip", inner}))
+
+	p := NewFileProcessor()
+	info, err := p.ProcessFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatches := info.MatchDetails["type_mismatch"]
+	if len(mismatches) != 1 || mismatches[0].Location.Attachment != "说明.txt" {
+		t.Errorf("附件的扩展名与内容不符没有上报: %+v", mismatches)
+	}
+
+	info, err = p.ProcessFile(outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phones := info.MatchDetails["phone"]
+	want := Location{Member: "outer.zip!/mail/b.eml!/inner.zip!/a.txt", MessageID: "<m3@example.com>", Attachment: "inner.zip"}
+	if len(phones) != 1 || *phones[0].Location != want {
+		t.Errorf("压缩包中邮件的附件中的命中为 %+v，期望位置 %+v", phones, want)
+	}
+
+	if err := p.sensMatch.Registry().SetEnabled("type_mismatch", false); err != nil {
+		t.Fatal(err)
+	}
+	info, err = p.ProcessFile(path)
+	if err == nil && len(info.MatchDetails["type_mismatch"]) > 0 {
+		t.Errorf("禁用 type_mismatch 后不应上报: %+v", info.MatchDetails)
+	}
+}
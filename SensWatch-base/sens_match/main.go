@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -16,65 +15,77 @@ import (
 
 // 全局变量
 var fileDict FileDict
-var results []SensitiveInfo
-
-// 不需要检测的文件扩展名
-var skipExtensions = map[string]bool{
-	".jpg":   true,
-	".jpeg":  true,
-	".png":   true,
-	".gif":   true,
-	".bmp":   true,
-	".ico":   true,
-	".svg":   true,
-	".webp":  true,
-	".mp3":   true,
-	".mp4":   true,
-	".avi":   true,
-	".mov":   true,
-	".wmv":   true,
-	".zip":   true,
-	".rar":   true,
-	".7z":    true,
-	".tar":   true,
-	".gz":    true,
-	".msi":   true,
-	".exe":   true,
-	".dll":   true,
-	".so":    true,
-	".dylib": true,
-}
 
 
 // FileProcessor 处理文件扫描和敏感信息检测
 type FileProcessor struct {
-	sensMatch *SensMatch
+	sensMatch        *SensMatch
+	masker           *Masker
+	bufferSize       int           // 每次读取的字节数
+	overlap          int           // 相邻读取窗口重叠的字节数
+	workers          int           // 并发扫描的文件数，<= 0 时使用 CPU 核数
+	maxInFlightBytes int64         // 同时处理的文件总大小上限，<= 0 表示不限制
+	policyVersion    string        // 策略的规则集版本
+	state            *ScanState    // 增量扫描状态，为 nil 时每次都完整扫描
+	archiveLimits    ArchiveLimits // 展开压缩包时的限制
 }
 
-// NewFileProcessor 创建新的 FileProcessor 实例
+// NewFileProcessor 使用内置默认策略创建新的 FileProcessor 实例
 func NewFileProcessor() *FileProcessor {
-	return &FileProcessor{
-		sensMatch: NewSensMatch(),
+	p, err := NewFileProcessorWithPolicy(DefaultPolicy())
+	if err != nil {
+		panic(fmt.Sprintf("加载默认策略失败: %v", err))
+	}
+	return p
+}
+
+// NewFileProcessorWithPolicy 根据策略创建新的 FileProcessor 实例。
+// 指纹密钥是随机生成的，需要跨进程稳定的指纹时用 SetMasker 替换
+func NewFileProcessorWithPolicy(policy *Policy) (*FileProcessor, error) {
+	sensMatch, err := NewSensMatchWithPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	masker, err := NewMasker(policy.Masking.Mode, nil)
+	if err != nil {
+		return nil, err
 	}
+	return &FileProcessor{
+		sensMatch:        sensMatch,
+		masker:           masker,
+		bufferSize:       defaultBufferSize,
+		overlap:          defaultOverlap,
+		maxInFlightBytes: defaultMaxInFlightBytes,
+		policyVersion:    policy.RuleSetVersion(),
+		archiveLimits:    defaultArchiveLimits,
+	}, nil
 }
 
-// shouldSkipFile 检查是否应该跳过该文件
-func shouldSkipFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	return skipExtensions[ext]
+// SetMasker 设置结果落盘前使用的打码器
+func (p *FileProcessor) SetMasker(masker *Masker) {
+	p.masker = masker
+}
+
+// SetScanWindow 设置流式读取的缓冲区大小和重叠窗口大小
+func (p *FileProcessor) SetScanWindow(bufferSize, overlap int) {
+	p.bufferSize = bufferSize
+	p.overlap = overlap
 }
 
 // ProcessFile 处理单个文件
 func (p *FileProcessor) ProcessFile(filePath string) (*SensitiveInfo, error) {
-	// 检查是否需要跳过该文件
-	if shouldSkipFile(filePath) {
-		return nil, fmt.Errorf("跳过不支持的文件类型: %s", filePath)
+	// 根据文件内容而不是扩展名识别文件类型
+	ctype, err := sniffFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("识别文件类型失败: %v", err)
 	}
+	mismatchRule := p.typeMismatchRuleID()
+	mismatch := mismatchRule != "" && extensionMismatch(filePath, ctype)
 
-	// 获取文件读取器
-	reader, err := GetFileReader(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("获取文件读取器失败: %v", err)
+	// 无法读取的类型跳过，但扩展名与内容不符时仍需上报
+	archive := p.archiveLimits.MaxDepth > 0 && isArchive(ctype)
+	if !ctype.Scannable() && !archive && !mismatch {
+		return nil, &unsupportedTypeError{path: filePath, ctype: ctype}
 	}
 
 	// 计算MD5
@@ -83,30 +94,49 @@ func (p *FileProcessor) ProcessFile(filePath string) (*SensitiveInfo, error) {
 		return nil, fmt.Errorf("计算MD5失败: %v", err)
 	}
 
-	// 流式读取文件内容
-	bufferSize := 4096 // 每次读取4KB
-	buffer := make([]byte, bufferSize)
-	var allMatches map[string][]string
-	allMatches = make(map[string][]string)
-
-	for {
-		n, err := reader.Read(buffer)
-		if err != nil && err != io.EOF {
-			return nil, fmt.Errorf("读取文件失败: %v", err)
+	// 压缩包展开后逐个扫描成员，成员的扩展名与内容不符同样上报
+	found := make(map[string][]Match)
+	var mismatches []Match
+	switch {
+	case archive:
+		if found, mismatches, err = p.scanArchive(filePath, ctype); err != nil {
+			return nil, err
 		}
-		if n == 0 {
-			break
+	case ctype.Scannable():
+		if found, mismatches, err = p.scanContent(filePath, ctype, p.archiveLimits); err != nil {
+			return nil, err
 		}
+	}
+	if mismatch {
+		mismatches = append([]Match{typeMismatchMatch(filePath, ctype)}, mismatches...)
+	}
+	if mismatchRule == "" {
+		mismatches = nil
+	}
 
-		// 对当前块进行敏感信息检测
-		chunkMatches := p.sensMatch.RunAllChecks(string(buffer[:n]))
+	// 过滤掉未达到最小命中次数的规则
+	for k, v := range found {
+		if rule, ok := p.sensMatch.Registry().Lookup(k); ok && len(v) < rule.MinHits() {
+			delete(found, k)
+		}
+	}
 
-		// 合并结果
-		for k, v := range chunkMatches {
-			allMatches[k] = append(allMatches[k], v...)
+	// 落盘前对命中值打码
+	allMatches := make(map[string][]string)
+	for k, matches := range found {
+		rule, _ := p.sensMatch.Registry().Lookup(k)
+		for i := range matches {
+			p.masker.Apply(rule, &matches[i])
+			allMatches[k] = append(allMatches[k], matches[i].Value)
 		}
 	}
 
+	// 扩展名与内容不符作为单独的命中，命中值不是敏感信息，不需要打码
+	for _, m := range mismatches {
+		found[mismatchRule] = append(found[mismatchRule], m)
+		allMatches[mismatchRule] = append(allMatches[mismatchRule], m.Value)
+	}
+
 	// 统计匹配数量
 	matchCounts := make(map[string]int)
 	totalCount := 0
@@ -114,46 +144,9 @@ func (p *FileProcessor) ProcessFile(filePath string) (*SensitiveInfo, error) {
 	for k, v := range allMatches {
 		matchCounts[k] = len(v)
 		totalCount += len(v)
-		// 根据规则名称获取规则编号
-		switch k {
-		case "phone":
-			ruleNumbersMap[1] = true
-		case "ip":
-			ruleNumbersMap[2] = true
-		case "mac":
-			ruleNumbersMap[3] = true
-		case "ipv6":
-			ruleNumbersMap[4] = true
-		case "bank_card":
-			ruleNumbersMap[5] = true
-		case "email":
-			ruleNumbersMap[6] = true
-		case "passport":
-			ruleNumbersMap[7] = true
-		case "id_number":
-			ruleNumbersMap[8] = true
-		case "gender":
-			ruleNumbersMap[9] = true
-		case "national":
-			ruleNumbersMap[10] = true
-		case "carnum":
-			ruleNumbersMap[11] = true
-		case "telephone":
-			ruleNumbersMap[12] = true
-		case "officer":
-			ruleNumbersMap[13] = true
-		case "HM_pass":
-			ruleNumbersMap[14] = true
-		case "jdbc":
-			ruleNumbersMap[15] = true
-		case "organization":
-			ruleNumbersMap[16] = true
-		case "business":
-			ruleNumbersMap[17] = true
-		case "credit":
-			ruleNumbersMap[18] = true
-		case "address_name":
-			ruleNumbersMap[19] = true
+		// 根据规则标识获取规则编号
+		if num, ok := p.sensMatch.Registry().RuleNumber(k); ok {
+			ruleNumbersMap[num] = true
 		}
 	}
 
@@ -188,168 +181,153 @@ func (p *FileProcessor) ProcessFile(filePath string) (*SensitiveInfo, error) {
 		DetectTime:          time.Now().Format("2006-01-02 15:04:05"),
 		MatchCounts:         matchCounts,
 		Matches:             allMatches,
+		MatchDetails:        found,
 		TotalSensitiveCount: totalCount,
 		RuleNumbers:         ruleNumbersStr,
 	}, nil
 }
 
-// ProcessFileList 处理文件列表
-func (p *FileProcessor) ProcessFileList(files []FileInfo) []SensitiveInfo {
-	results = nil // 清空之前的结果
+// scanContent 按类型读取文件内容并匹配所有规则，同时返回文件内部（如邮件附件）扩展名与内容不符的文件。
+// limits 为展开文件内部的压缩包时的限制
+func (p *FileProcessor) scanContent(filePath string, ctype ContentType, limits ArchiveLimits) (map[string][]Match, []Match, error) {
+	reader, err := readerForType(filePath, ctype, limits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取文件读取器失败: %v", err)
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// 流式读取文件内容，相邻窗口重叠以免漏掉跨越缓冲区边界的命中
+	scanner := NewStreamScanner(p.sensMatch, p.bufferSize, p.overlap)
+	scanner.SetMasker(p.masker)
+	found, err := scanner.Scan(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	var mismatches []Match
+	if reporter, ok := reader.(mismatchReporter); ok {
+		mismatches = reporter.Mismatches()
+	}
+	return found, mismatches, nil
+}
+
+// ScanScope 记录一次全量扫描覆盖的目录和文件，导出数据库和保存扫描状态时据此清理旧记录
+type ScanScope struct {
+	Roots  []string        // 本次遍历的目录（绝对路径），其它目录下的记录不清理
+	Listed map[string]bool // 本次文件列表中的文件（绝对路径）
+	Clean  map[string]bool // 成功扫描且不包含敏感信息的文件，扫描失败的文件不在其中
+}
+
+// Contains 返回 path 是否在本次遍历的目录下
+func (s *ScanScope) Contains(path string) bool {
+	for _, root := range s.Roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Gone 返回 path 是否在本次遍历的目录下、但已不在文件列表中，即文件已被删除或不再需要扫描
+func (s *ScanScope) Gone(path string) bool {
+	return !s.Listed[path] && s.Contains(path)
+}
+
+// ProcessFileList 并发处理文件列表，结果按输入顺序返回，同时返回本次扫描覆盖的文件。
+// roots 为文件列表来自的目录，只清理这些目录下已不在列表中的文件的扫描状态
+func (p *FileProcessor) ProcessFileList(files []FileInfo, roots []string) ([]SensitiveInfo, *ScanScope) {
+	// 文件类型根据内容识别，不支持的类型由 ProcessFile 跳过
+	var results []SensitiveInfo
+	scanned := p.scanFiles(files)
+	scope := &ScanScope{Listed: make(map[string]bool), Clean: make(map[string]bool)}
+	for _, root := range roots {
+		if absRoot, err := filepath.Abs(root); err == nil {
+			scope.Roots = append(scope.Roots, absRoot)
+		}
+	}
 	for _, file := range files {
-		// 检查是否需要跳过该文件
-		if shouldSkipFile(file.Path) {
-			fmt.Printf("跳过不支持的文件类型: %s\n", file.Path)
-			continue
+		if absPath, err := filepath.Abs(file.Path); err == nil {
+			scope.Listed[absPath] = true
 		}
+	}
 
-		info, err := p.ProcessFile(file.Path)
-		if err != nil {
-			fmt.Printf("处理文件 %s 失败: %v\n", file.Path, err)
+	// 保存增量扫描状态，并清理遍历的目录下已不在列表中的文件
+	if p.state != nil {
+		p.state.Prune(scope)
+		if err := p.state.Flush(); err != nil {
+			fmt.Printf("保存扫描状态失败: %v\n", err)
+		}
+	}
+
+	for i, r := range scanned {
+		absPath, _ := filepath.Abs(files[i].Path)
+		if _, ok := r.err.(*unsupportedTypeError); ok {
+			fmt.Println(r.err)
+			scope.Clean[absPath] = true
+			continue
+		}
+		if r.err != nil {
+			fmt.Printf("处理文件 %s 失败: %v\n", files[i].Path, r.err)
 			continue
 		}
 		// 只添加包含敏感信息的文件
-		if info.TotalSensitiveCount > 0 {
-			results = append(results, *info)
+		if r.info.TotalSensitiveCount > 0 {
+			results = append(results, *r.info)
 		} else {
-			fmt.Printf("跳过不包含敏感信息的文件: %s\n", file.Path)
+			fmt.Printf("跳过不包含敏感信息的文件: %s\n", files[i].Path)
+			scope.Clean[absPath] = true
 		}
 	}
-	return results
+	return results, scope
 }
 
-// SaveResults 保存结果到JSON文件和SQLite数据库
-func (p *FileProcessor) SaveResults(results []SensitiveInfo, outputFile string) error {
-	// 如果没有包含敏感信息的文件，直接返回
+// SaveResults 保存结果到JSON文件和SQLite数据库，scope 为本次扫描覆盖的文件
+func (p *FileProcessor) SaveResults(results []SensitiveInfo, scope *ScanScope, outputFile, sqlitePath string) error {
+	// 如果没有包含敏感信息的文件，不生成输出文件，但数据库中的旧记录仍需清理
 	if len(results) == 0 {
 		fmt.Println("没有发现包含敏感信息的文件，不生成输出文件")
-		return nil
-	}
-
-	// 保存到JSON文件
-	data, err := json.MarshalIndent(results, "", "    ")
-	if err != nil {
-		return fmt.Errorf("序列化结果失败: %v", err)
-	}
+	} else {
+		// 保存到JSON文件
+		data, err := json.MarshalIndent(results, "", "    ")
+		if err != nil {
+			return fmt.Errorf("序列化结果失败: %v", err)
+		}
 
-	if err := ioutil.WriteFile(outputFile, data, 0644); err != nil {
-		return fmt.Errorf("写入结果文件失败: %v", err)
+		if err := ioutil.WriteFile(outputFile, data, 0644); err != nil {
+			return fmt.Errorf("写入结果文件失败: %v", err)
+		}
 	}
 
 	// 尝试导出到SQLite数据库
-	sqlitePath := strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".db"
 	fmt.Printf("尝试导出SQLite到: %s\n", sqlitePath)
 
-	if err := ExportToSQLite(sqlitePath, results); err != nil {
-		fmt.Printf("导出到SQLite失败: %v\n", err)
+	if err := ExportToSQLite(sqlitePath, results, scope); err != nil {
 		if strings.Contains(err.Error(), "cgo") {
 			fmt.Println("提示: 请使用 'set CGO_ENABLED=1' 重新编译程序")
 		}
-	} else {
-		fmt.Printf("成功导出SQLite数据库: %s\n", sqlitePath)
+		return fmt.Errorf("导出到SQLite失败: %v", err)
 	}
+	fmt.Printf("成功导出SQLite数据库: %s\n", sqlitePath)
 
 	return nil
 }
 
-// LogTracker 用于跟踪日志文件的变化
-type LogTracker struct {
-	lastPosition int64
-	logFile      string
-}
-
 // FileChange 表示文件变化信息
 type FileChange struct {
-	Type     string // "modify", "move", "delete"
+	Type     string // "create", "modify", "move", "delete"
 	OldPath  string // 移动/重命名前的路径
 	NewPath  string // 移动/重命名后的路径
-	FilePath string // 修改/删除的文件路径
-}
-
-// NewLogTracker 创建新的日志跟踪器
-func NewLogTracker(logFile string) *LogTracker {
-	return &LogTracker{
-		lastPosition: 0,
-		logFile:      logFile,
-	}
-}
-
-// getModifiedFiles 获取自上次检查以来修改过的文件
-func (lt *LogTracker) getModifiedFiles() ([]FileChange, error) {
-	file, err := os.Open(lt.logFile)
-	if err != nil {
-		return nil, fmt.Errorf("打开日志文件失败: %v", err)
-	}
-	defer file.Close()
-
-	// 获取文件当前大小
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("获取文件信息失败: %v", err)
-	}
-
-	// 如果文件大小小于上次位置，说明文件被清空或重置
-	if fileInfo.Size() < lt.lastPosition {
-		lt.lastPosition = 0
-	}
-
-	// 移动到上次处理的位置
-	if _, err := file.Seek(lt.lastPosition, 0); err != nil {
-		return nil, fmt.Errorf("移动文件指针失败: %v", err)
-	}
-
-	// 读取新的日志内容
-	scanner := bufio.NewScanner(file)
-	var changes []FileChange
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, "文件修改事件:") {
-			parts := strings.Split(line, "文件修改事件: ")
-			if len(parts) > 1 {
-				changes = append(changes, FileChange{
-					Type:     "modify",
-					FilePath: parts[1],
-				})
-			}
-		} else if strings.Contains(line, "文件移动/重命名事件:") {
-			parts := strings.Split(line, "文件移动/重命名事件: ")
-			if len(parts) > 1 {
-				pathParts := strings.Split(parts[1], " -> ")
-				if len(pathParts) == 2 {
-					changes = append(changes, FileChange{
-						Type:    "move",
-						OldPath: pathParts[0],
-						NewPath: pathParts[1],
-					})
-				}
-			}
-		} else if strings.Contains(line, "文件删除事件:") {
-			parts := strings.Split(line, "文件删除事件: ")
-			if len(parts) > 1 {
-				changes = append(changes, FileChange{
-					Type:     "delete",
-					FilePath: parts[1],
-				})
-			}
-		}
-	}
-
-	// 更新最后处理的位置
-	lt.lastPosition, err = file.Seek(0, 1)
-	if err != nil {
-		return nil, fmt.Errorf("获取当前位置失败: %v", err)
-	}
-
-	return changes, nil
+	FilePath string // 创建/修改/删除的文件路径
+	IsDir    bool   // 删除的是整个目录
 }
 
 // updateDatabase 更新数据库
-func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile string) error {
+func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, sqlitePath string) error {
 	// 打开数据库连接
-	sqlitePath := strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".db"
-	db, err := sql.Open("sqlite3", sqlitePath)
+	db, err := openDatabase(sqlitePath)
 	if err != nil {
-		return fmt.Errorf("打开数据库失败: %v", err)
+		return err
 	}
 	defer db.Close()
 
@@ -360,17 +338,12 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 	}
 	defer tx.Rollback()
 
-	// 准备SQLite插入语句
-	insertSQL := `
-	INSERT OR REPLACE INTO detection_results 
-	(file_path, file_name, md5, detect_time, match_counts, matches, total_sensitive_count, rule_numbers)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	stmt, err := tx.Prepare(insertSQL)
+	// 准备写入检测结果
+	writer, err := newResultWriter(tx, SourceRealtime)
 	if err != nil {
-		return fmt.Errorf("准备SQLite插入语句失败: %v", err)
+		return err
 	}
-	defer stmt.Close()
+	defer writer.Close()
 
 	// 处理需要扫描的文件
 	for filePath, shouldScan := range filesToScan {
@@ -383,8 +356,17 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 				return fmt.Errorf("查询数据库失败: %v", err)
 			}
 
+			if p.state != nil {
+				if absPath, err := filepath.Abs(filePath); err == nil {
+					p.state.Remove(absPath)
+				}
+			}
+
 			// 如果数据库中存在该文件，需要删除记录
 			if count > 0 {
+				if err := recordRemovalEvent(tx, EventDelete, filePath, "", SourceRealtime); err != nil {
+					return err
+				}
 				_, err = tx.Exec("DELETE FROM detection_results WHERE file_path = ?", filePath)
 				if err != nil {
 					return fmt.Errorf("删除文件记录失败: %v", err)
@@ -394,7 +376,7 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 			continue
 		}
 
-		info, err := p.ProcessFile(filePath)
+		info, err := p.processIncremental(filePath)
 		if err != nil {
 			fmt.Printf("处理文件 %s 失败: %v\n", filePath, err)
 			continue
@@ -402,28 +384,8 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 
 		if info.TotalSensitiveCount > 0 {
 			// 如果文件包含敏感信息，更新数据库
-			matchCountsJSON, err := json.Marshal(info.MatchCounts)
-			if err != nil {
-				return fmt.Errorf("转换match_counts为JSON失败: %v", err)
-			}
-
-			matchesJSON, err := json.Marshal(info.Matches)
-			if err != nil {
-				return fmt.Errorf("转换matches为JSON失败: %v", err)
-			}
-
-			// 执行插入或更新
-			_, err = stmt.Exec(
-				info.FilePath,
-				info.FileName,
-				info.MD5,
-				info.DetectTime,
-				string(matchCountsJSON),
-				string(matchesJSON),
-				info.TotalSensitiveCount,
-				info.RuleNumbers)
-			if err != nil {
-				return fmt.Errorf("插入数据到SQLite失败: %v", err)
+			if err := writer.Write(*info); err != nil {
+				return err
 			}
 		} else {
 			// 如果文件不再包含敏感信息，检查数据库中是否存在该文件
@@ -435,6 +397,9 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 
 			// 如果数据库中存在该文件，说明它从敏感文件变成了非敏感文件，需要删除
 			if count > 0 {
+				if err := recordRemovalEvent(tx, EventCleared, filePath, "", SourceRealtime); err != nil {
+					return err
+				}
 				_, err = tx.Exec("DELETE FROM detection_results WHERE file_path = ?", filePath)
 				if err != nil {
 					return fmt.Errorf("删除非敏感文件记录失败: %v", err)
@@ -449,6 +414,12 @@ func (p *FileProcessor) updateDatabase(filesToScan map[string]bool, outputFile s
 		return fmt.Errorf("提交事务失败: %v", err)
 	}
 
+	if p.state != nil {
+		if err := p.state.Flush(); err != nil {
+			return fmt.Errorf("保存扫描状态失败: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -467,6 +438,10 @@ func (p *FileProcessor) processFileChanges(changes []FileChange, db *sql.DB) (ma
 	// 处理每个变化
 	for _, change := range changes {
 		switch change.Type {
+		case "create":
+			// 新文件直接扫描，扫描结果写入数据库
+			filesToScan[change.FilePath] = true
+
 		case "modify":
 			// 将修改的文件添加到扫描集合
 			filesToScan[change.FilePath] = true
@@ -480,9 +455,20 @@ func (p *FileProcessor) processFileChanges(changes []FileChange, db *sql.DB) (ma
 			}
 
 			if count > 0 {
-				// 如果旧路径在数据库中，更新为新路径
-				_, err = tx.Exec("UPDATE detection_results SET file_path = ? WHERE file_path = ?",
-					change.NewPath, change.OldPath)
+				// 目标路径上原有的文件已被覆盖，先删除它的记录
+				if err := recordRemovalEvent(tx, EventDelete, change.NewPath, "", SourceRealtime); err != nil {
+					return nil, err
+				}
+				if err := recordRemovalEvent(tx, EventMove, change.OldPath, change.NewPath, SourceRealtime); err != nil {
+					return nil, err
+				}
+				_, err = tx.Exec("DELETE FROM detection_results WHERE file_path = ?", change.NewPath)
+				if err != nil {
+					return nil, fmt.Errorf("删除被覆盖的文件记录失败: %v", err)
+				}
+				// 如果旧路径在数据库中，更新为新路径，文件名随之更新
+				_, err = tx.Exec("UPDATE detection_results SET file_path = ?, file_name = ? WHERE file_path = ?",
+					change.NewPath, filepath.Base(change.NewPath), change.OldPath)
 				if err != nil {
 					return nil, fmt.Errorf("更新文件路径失败: %v", err)
 				}
@@ -493,7 +479,20 @@ func (p *FileProcessor) processFileChanges(changes []FileChange, db *sql.DB) (ma
 			filesToScan[change.OldPath] = false
 
 		case "delete":
-			// 从数据库中删除记录
+			// 从数据库中删除记录，删除的是目录时一并删除其下所有文件的记录
+			if change.IsDir {
+				prefix := change.FilePath + string(filepath.Separator)
+				if err := recordDirRemovalEvents(tx, prefix); err != nil {
+					return nil, err
+				}
+				_, err = tx.Exec("DELETE FROM detection_results WHERE substr(file_path, 1, length(?)) = ?", prefix, prefix)
+				if err != nil {
+					return nil, fmt.Errorf("删除目录记录失败: %v", err)
+				}
+			}
+			if err := recordRemovalEvent(tx, EventDelete, change.FilePath, "", SourceRealtime); err != nil {
+				return nil, err
+			}
 			_, err = tx.Exec("DELETE FROM detection_results WHERE file_path = ?", change.FilePath)
 			if err != nil {
 				return nil, fmt.Errorf("删除文件记录失败: %v", err)
@@ -510,35 +509,54 @@ func (p *FileProcessor) processFileChanges(changes []FileChange, db *sql.DB) (ma
 	return filesToScan, nil
 }
 
-func realTimeMatch() {
-	// 创建日志跟踪器
-	exeDir, err := os.Getwd()
-	baseDir := filepath.Dir(exeDir)
-	log_path := filepath.Join(baseDir, "simple_everything.log")
-	tracker := NewLogTracker(log_path)
-	processor := NewFileProcessor()
+// realTimeMatch 监控 roots 下的文件变化，文件安静 quiet 时间后把合并后的变化同步到数据库
+func realTimeMatch(processor *FileProcessor, roots []string, sqlitePath string, quiet time.Duration) {
+	watcher, err := NewWatcher(roots)
+	if err != nil {
+		fmt.Printf("启动文件监控失败: %v\n", err)
+		return
+	}
+	defer watcher.Close()
+	for _, root := range roots {
+		fmt.Printf("已启动对目录 %s 的监控\n", root)
+	}
 
 	// 打开数据库连接
-	sqlitePath := filepath.Join(baseDir, "output.db")
-	db, err := sql.Open("sqlite3", sqlitePath)
+	db, err := openDatabase(sqlitePath)
 	if err != nil {
 		fmt.Printf("打开数据库失败: %v\n", err)
 		return
 	}
 	defer db.Close()
 
+	// 合并短时间内的连续变化，避免扫描写了一半的文件
+	coalescer := NewCoalescer(quiet)
+	interval := quiet / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
-		// 等待3秒后再次检查
-		time.Sleep(3 * time.Second)
+		select {
+		case change, ok := <-watcher.Changes():
+			if !ok {
+				return
+			}
+			coalescer.Add(change, time.Now())
 
-		// 获取文件变化
-		changes, err := tracker.getModifiedFiles()
-		if err != nil {
-			fmt.Printf("获取文件变化失败: %v\n", err)
-			continue
-		}
+		case err := <-watcher.Errors():
+			fmt.Printf("文件监控出错: %v\n", err)
 
-		if len(changes) > 0 {
+		case <-ticker.C:
+			if coalescer.Pending() == 0 {
+				continue
+			}
+			changes := coalescer.Ready(time.Now())
+			if len(changes) == 0 {
+				continue
+			}
 			fmt.Printf("检测到 %d 个文件变化\n", len(changes))
 
 			// 处理文件变化，获取需要扫描的文件集合
@@ -549,9 +567,7 @@ func realTimeMatch() {
 			}
 
 			// 更新数据库
-			rootDir := filepath.Dir(filepath.Dir(exeDir))
-			outputFile := filepath.Join(rootDir, "output.json")
-			if err := processor.updateDatabase(filesToScan, outputFile); err != nil {
+			if err := processor.updateDatabase(filesToScan, sqlitePath); err != nil {
 				fmt.Printf("更新数据库失败: %v\n", err)
 			} else {
 				fmt.Printf("成功更新数据库，处理了 %d 个文件\n", len(filesToScan))
@@ -561,33 +577,5 @@ func realTimeMatch() {
 }
 
 func main() {
-	exeDir, err := os.Getwd()
-	if err != nil {
-		fmt.Printf("获取当前工作目录失败: %v\n", err)
-		os.Exit(1)
-	}
-	rootDir := filepath.Dir(exeDir)
-	inputFile := filepath.Join(rootDir, "file_index.json")
-	outputFile := filepath.Join(rootDir, "output.json")
-
-	// 读取输入文件列表
-	fileList, err := ReadFileList(inputFile)
-	if err != nil {
-		fmt.Printf("读取文件列表失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// 处理文件
-	processor := NewFileProcessor()
-	results := processor.ProcessFileList(fileList)
-
-	// 保存结果
-	if err := processor.SaveResults(results, outputFile); err != nil {
-		fmt.Printf("保存结果失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Printf("处理完成，结果已保存到: %s\n", outputFile)
-
-	realTimeMatch()
+	os.Exit(runCLI(os.Args[1:]))
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MaskStrategy 表示单条规则命中值的打码方式
+type MaskStrategy string
+
+const (
+	MaskPartial MaskStrategy = "partial" // 保留首尾少量字符，如 138****5678
+	MaskHMAC    MaskStrategy = "hmac"    // 只保存带密钥的 HMAC 指纹
+	MaskRedact  MaskStrategy = "redact"  // 完全隐藏
+)
+
+// StoreMode 是全局开关，决定写入 output.json / output.db 的命中值
+type StoreMode string
+
+const (
+	StorePlain       StoreMode = "plain"       // 保存明文（旧行为）
+	StoreMasked      StoreMode = "masked"      // 按每条规则的打码方式保存
+	StoreFingerprint StoreMode = "fingerprint" // 所有规则都只保存指纹
+	StoreRedact      StoreMode = "redact"      // 所有规则都完全隐藏
+)
+
+// redactedValue 完全隐藏时保存的占位符
+const redactedValue = "[REDACTED]"
+
+// maskKeyEnv 指纹密钥的环境变量，十六进制编码
+const maskKeyEnv = "SENS_MATCH_KEY"
+
+// Masker 在结果落盘前对命中值打码并计算指纹
+type Masker struct {
+	mode StoreMode
+	key  []byte
+}
+
+// NewMasker 创建打码器。key 为空时生成随机密钥，此时指纹只在本进程内稳定
+func NewMasker(mode StoreMode, key []byte) (*Masker, error) {
+	switch mode {
+	case "":
+		mode = StoreMasked
+	case StorePlain, StoreMasked, StoreFingerprint, StoreRedact:
+	default:
+		return nil, fmt.Errorf("未知的保存模式: %s", mode)
+	}
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("生成指纹密钥失败: %v", err)
+		}
+	}
+	return &Masker{mode: mode, key: key}, nil
+}
+
+// Fingerprint 计算命中值的 HMAC-SHA256 指纹。相同的值和密钥总是得到相同的指纹，
+// 可以在不保存明文的情况下去重和检索
+func (m *Masker) Fingerprint(value string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// strategy 返回规则在当前保存模式下实际使用的打码方式，空字符串表示保存明文
+func (m *Masker) strategy(rule Rule) MaskStrategy {
+	switch m.mode {
+	case StorePlain:
+		return ""
+	case StoreFingerprint:
+		return MaskHMAC
+	case StoreRedact:
+		return MaskRedact
+	}
+	if rule == nil || rule.Mask() == "" {
+		return MaskPartial
+	}
+	return rule.Mask()
+}
+
+// Apply 计算命中的指纹并按规则的打码方式替换命中值
+func (m *Masker) Apply(rule Rule, match *Match) {
+	match.Fingerprint = m.Fingerprint(match.Value)
+	switch m.strategy(rule) {
+	case MaskPartial:
+		match.Value = maskPartial(match.Value)
+	case MaskHMAC:
+		match.Value = "hmac:" + match.Fingerprint
+		match.Context = ""
+	case MaskRedact:
+		match.Value = redactedValue
+		match.Context = ""
+	}
+}
+
+// LoadMaskKey 读取已有的指纹密钥。优先使用环境变量 SENS_MATCH_KEY，其次读取 path，
+// 密钥不存在时返回错误而不是生成新密钥，用于只读的查询
+func LoadMaskKey(path string) ([]byte, error) {
+	key, err := readMaskKey(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("密钥文件 %s 不存在，请先运行扫描或用 -key 指定扫描时使用的密钥", path)
+	}
+	return key, err
+}
+
+// LoadOrCreateMaskKey 读取指纹密钥。优先使用环境变量 SENS_MATCH_KEY，
+// 其次读取 path，文件不存在时生成新密钥并保存，保证多次运行的指纹一致
+func LoadOrCreateMaskKey(path string) ([]byte, error) {
+	key, err := readMaskKey(path)
+	if !os.IsNotExist(err) {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成指纹密钥失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return nil, fmt.Errorf("保存密钥文件失败: %v", err)
+	}
+	return key, nil
+}
+
+// readMaskKey 从环境变量或 path 读取指纹密钥，文件不存在时原样返回 os.ReadFile 的错误
+func readMaskKey(path string) ([]byte, error) {
+	if env := os.Getenv(maskKeyEnv); env != "" {
+		key, err := hex.DecodeString(strings.TrimSpace(env))
+		if err != nil {
+			return nil, fmt.Errorf("环境变量 %s 不是有效的十六进制: %v", maskKeyEnv, err)
+		}
+		return key, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("密钥文件 %s 内容无效: %v", path, err)
+	}
+	return key, nil
+}
+
+// maskPartial 保留首尾少量字符，中间替换为 *，如 138****5678
+func maskPartial(value string) string {
+	runes := []rune(value)
+	n := len(runes)
+	var head, tail int
+	switch {
+	case n >= 11:
+		head, tail = 3, 4
+	case n >= 6:
+		head, tail = 2, 2
+	case n >= 3:
+		head, tail = 1, 1
+	}
+	return string(runes[:head]) + strings.Repeat("*", n-head-tail) + string(runes[n-tail:])
+}
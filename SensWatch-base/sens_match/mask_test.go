@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaskPartial(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"13812345678", "138****5678"},
+		{"110101199003077777", "110***********7777"},
+		{"张三丰", "张*丰"},
+		{"a@b.cn", "a@**cn"},
+		{"ab", "**"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := maskPartial(tt.in); got != tt.want {
+			t.Errorf("maskPartial(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskerApply(t *testing.T) {
+	registry := NewSensMatch().Registry()
+	phone, _ := registry.Lookup("phone")
+	jdbc, _ := registry.Lookup("jdbc")
+	key := []byte("0123456789abcdef")
+	fingerprint := (&Masker{key: key}).Fingerprint("13812345678")
+
+	tests := []struct {
+		name  string
+		mode  StoreMode
+		rule  Rule
+		value string
+		ctx   string
+	}{
+		{"按规则 partial", StoreMasked, phone, "138****5678", "上下文"},
+		{"按规则 redact", StoreMasked, jdbc, redactedValue, ""},
+		{"明文", StorePlain, jdbc, "13812345678", "上下文"},
+		{"只保存指纹", StoreFingerprint, phone, "hmac:" + fingerprint, ""},
+		{"全部隐藏", StoreRedact, phone, redactedValue, ""},
+	}
+	for _, tt := range tests {
+		m, err := NewMasker(tt.mode, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := Match{Value: "13812345678", Context: "上下文"}
+		m.Apply(tt.rule, &match)
+		if match.Value != tt.value || match.Context != tt.ctx || match.Fingerprint != fingerprint {
+			t.Errorf("%s: 打码后为 %+v，期望值 %q 上下文 %q", tt.name, match, tt.value, tt.ctx)
+		}
+	}
+
+	if _, err := NewMasker("md5", key); err == nil {
+		t.Error("未知的保存模式应当报错")
+	}
+}
+
+func TestStreamScannerContextMask(t *testing.T) {
+	s := NewSensMatch()
+	text := "电话13812345678 连接 jdbc:mysql://db.example.com:3306/hr?user=admin&password=secret"
+
+	tests := []struct {
+		mode    StoreMode
+		want    string
+		notWant string
+	}{
+		{StoreMasked, "138****5678", "secret"},
+		{StoreRedact, redactedValue, "5678"},
+		{StorePlain, "13812345678", ""},
+	}
+	for _, tt := range tests {
+		masker, err := NewMasker(tt.mode, []byte("key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := NewStreamScanner(s, 4096, 256)
+		sc.SetMasker(masker)
+		results, err := sc.Scan(strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		jdbc := results["jdbc"]
+		if len(jdbc) != 1 {
+			t.Fatalf("%s: jdbc 命中为 %+v", tt.mode, jdbc)
+		}
+		// jdbc 命中的上下文中包含手机号，手机号按 phone 规则打码
+		ctx := jdbc[0].Context
+		if !strings.Contains(ctx, tt.want) || (tt.notWant != "" && strings.Contains(ctx, tt.notWant)) {
+			t.Errorf("%s: 上下文为 %q", tt.mode, ctx)
+		}
+	}
+}
+
+func TestLoadMaskKey(t *testing.T) {
+	t.Setenv(maskKeyEnv, "")
+	path := filepath.Join(t.TempDir(), "sens_match.key")
+
+	if _, err := LoadMaskKey(path); err == nil {
+		t.Error("密钥文件不存在时只读加载应当报错")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("只读加载不应创建密钥文件")
+	}
+
+	created, err := LoadOrCreateMaskKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadMaskKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(created) != string(loaded) || len(loaded) != 32 {
+		t.Errorf("再次读取的密钥与生成的不一致")
+	}
+
+	t.Setenv(maskKeyEnv, "00ff")
+	if key, err := LoadMaskKey(path); err != nil || string(key) != "\x00\xff" {
+		t.Errorf("环境变量中的密钥为 %x，err = %v", key, err)
+	}
+}
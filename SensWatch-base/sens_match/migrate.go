@@ -0,0 +1,298 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// migration 表示一次数据库结构升级。已发布的升级不能再修改，只能追加新的升级
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations 按版本号排列的数据库升级
+var migrations = []migration{
+	{
+		version:     1,
+		description: "创建检测结果表",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS detection_results (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				file_path TEXT NOT NULL UNIQUE,
+				file_name TEXT NOT NULL,
+				md5 TEXT NOT NULL,
+				detect_time DATETIME NOT NULL,
+				match_counts TEXT,
+				matches TEXT,
+				total_sensitive_count INTEGER DEFAULT 0,
+				rule_numbers TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			`)
+			return err
+		},
+	},
+	{
+		version:     2,
+		description: "检测结果表增加 match_details 列",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "detection_results", "match_details", "TEXT")
+		},
+	},
+	{
+		version:     3,
+		description: "创建增量扫描状态表",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS scan_state (
+				file_path TEXT PRIMARY KEY,
+				size INTEGER NOT NULL,
+				modified_time REAL NOT NULL,
+				md5 TEXT NOT NULL,
+				rule_version TEXT NOT NULL,
+				result TEXT,
+				scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			`)
+			return err
+		},
+	},
+	{
+		version:     4,
+		description: "创建只追加的检测事件表",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS detection_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				event_time DATETIME NOT NULL,
+				event_type TEXT NOT NULL,
+				file_path TEXT NOT NULL,
+				old_path TEXT,
+				md5 TEXT,
+				total_sensitive_count INTEGER DEFAULT 0,
+				rule_numbers TEXT,
+				match_counts TEXT,
+				source TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_detection_events_file_path ON detection_events (file_path, event_time);
+			CREATE INDEX IF NOT EXISTS idx_detection_events_old_path ON detection_events (old_path);
+			CREATE TRIGGER IF NOT EXISTS detection_events_no_update BEFORE UPDATE ON detection_events
+			BEGIN
+				SELECT RAISE(ABORT, 'detection_events 只允许追加');
+			END;
+			CREATE TRIGGER IF NOT EXISTS detection_events_no_delete BEFORE DELETE ON detection_events
+			BEGIN
+				SELECT RAISE(ABORT, 'detection_events 只允许追加');
+			END;
+			`)
+			return err
+		},
+	},
+	{
+		version:     5,
+		description: "创建命中明细表",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS matches (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				file_id INTEGER NOT NULL REFERENCES detection_results (id),
+				rule_id TEXT NOT NULL,
+				value TEXT,
+				fingerprint TEXT,
+				byte_offset INTEGER,
+				line INTEGER,
+				page INTEGER,
+				sheet TEXT,
+				cell TEXT,
+				slide INTEGER,
+				context TEXT
+			);
+			CREATE INDEX IF NOT EXISTS idx_matches_file_id ON matches (file_id);
+			CREATE INDEX IF NOT EXISTS idx_matches_fingerprint ON matches (fingerprint);
+			CREATE INDEX IF NOT EXISTS idx_matches_rule_id ON matches (rule_id);
+			CREATE TRIGGER IF NOT EXISTS detection_results_delete_matches AFTER DELETE ON detection_results
+			BEGIN
+				DELETE FROM matches WHERE file_id = OLD.id;
+			END;
+			`)
+			if err != nil {
+				return err
+			}
+			return backfillMatches(tx)
+		},
+	},
+	{
+		version:     6,
+		description: "命中明细表增加 member 列",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "matches", "member", "TEXT")
+		},
+	},
+	{
+		version:     7,
+		description: "命中明细表增加 message_id、attachment 列",
+		up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "matches", "message_id", "TEXT"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "matches", "attachment", "TEXT")
+		},
+	},
+	{
+		version:     8,
+		description: "命中明细表增加 part 列",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "matches", "part", "TEXT")
+		},
+	},
+}
+
+// openDatabase 打开数据库并升级到最新结构
+func openDatabase(sqlitePath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", sqlitePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	if err := migrateSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrateSchema 依次执行尚未执行的升级，每个升级在单独的事务中完成。
+// 旧版本程序创建的数据库没有 schema_version 表，视为版本 0
+func migrateSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("创建schema_version表失败: %v", err)
+	}
+
+	var current int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return fmt.Errorf("查询数据库版本失败: %v", err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("数据库版本 %d 高于程序支持的版本 %d，请升级程序", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("开始事务失败: %v", err)
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("数据库升级到版本 %d（%s）失败: %v", m.version, m.description, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version, description) VALUES (?, ?)", m.version, m.description); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("记录数据库版本失败: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("提交事务失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// insertMatchV5SQL 版本 5 的命中明细表的插入语句。backfillMatches 属于已发布的升级 5，
+// 不能使用随后续版本变化的 insertMatchSQL
+const insertMatchV5SQL = `
+INSERT INTO matches
+(file_id, rule_id, value, fingerprint, byte_offset, line, page, sheet, cell, slide, context)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// backfillMatches 根据已有记录的 match_details 填充版本 5 的命中明细表
+func backfillMatches(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, match_details FROM detection_results WHERE match_details IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	details := make(map[int64]map[string][]Match)
+	for rows.Next() {
+		var fileID int64
+		var data string
+		if err := rows.Scan(&fileID, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		var d map[string][]Match
+		if json.Unmarshal([]byte(data), &d) == nil && len(d) > 0 {
+			details[fileID] = d
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(insertMatchV5SQL)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for fileID, d := range details {
+		for ruleID, matches := range d {
+			for _, m := range matches {
+				var page, slide sql.NullInt64
+				var sheet, cell sql.NullString
+				if loc := m.Location; loc != nil {
+					page = sql.NullInt64{Int64: int64(loc.Page), Valid: loc.Page > 0}
+					slide = sql.NullInt64{Int64: int64(loc.Slide), Valid: loc.Slide > 0}
+					sheet = nullString(loc.Sheet)
+					cell = nullString(loc.Cell)
+				}
+				_, err := stmt.Exec(fileID, ruleID, m.Value, nullString(m.Fingerprint), m.Offset, m.Line,
+					page, sheet, cell, slide, nullString(m.Context))
+				if err != nil {
+					return fmt.Errorf("写入命中明细失败: %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing 表中没有该列时添加
+func addColumnIfMissing(tx *sql.Tx, table, column, columnType string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnType))
+	return err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// openAtVersion 创建只升级到 version 的数据库，模拟旧版本程序留下的数据库
+func openAtVersion(t *testing.T, version int) (*sql.DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "output.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE schema_version (version INTEGER PRIMARY KEY, description TEXT, applied_at DATETIME DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.version > version {
+			break
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.up(tx); err != nil {
+			t.Fatalf("升级到版本 %d 失败: %v", m.version, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version, description) VALUES (?, ?)", m.version, m.description); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, path
+}
+
+func TestMigrateSchema(t *testing.T) {
+	latest := migrations[len(migrations)-1].version
+	for _, from := range []int{0, 1, 4, 5, latest} {
+		old, path := openAtVersion(t, from)
+		if from >= 2 {
+			_, err := old.Exec(`INSERT INTO detection_results (file_path, file_name, md5, detect_time, match_details)
+			VALUES ('/data/a.xlsx', 'a.xlsx', 'md5', '2024-01-01 00:00:00',
+			'{"phone":[{"value":"138****5678","offset":3,"line":1,"location":{"sheet":"Sheet1","cell":"B2"},"context":"x"}]}')`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		old.Close()
+
+		db, err := openDatabase(path)
+		if err != nil {
+			t.Fatalf("从版本 %d 升级失败: %v", from, err)
+		}
+		var version int
+		if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != latest {
+			t.Errorf("从版本 %d 升级后版本为 %d，期望 %d", from, version, latest)
+		}
+		// 最新的插入语句可以使用，说明后续版本增加的列都已存在
+		stmt, err := db.Prepare(insertMatchSQL)
+		if err != nil {
+			t.Errorf("从版本 %d 升级后缺少列: %v", from, err)
+		} else {
+			stmt.Close()
+		}
+
+		// 升级 5 根据 match_details 回填命中明细
+		if from >= 2 && from < 5 {
+			var ruleID, sheet, cell string
+			err := db.QueryRow("SELECT rule_id, sheet, cell FROM matches").Scan(&ruleID, &sheet, &cell)
+			if err != nil {
+				t.Errorf("从版本 %d 升级后没有回填命中明细: %v", from, err)
+			} else if ruleID != "phone" || sheet != "Sheet1" || cell != "B2" {
+				t.Errorf("回填的命中明细为 %s %s %s", ruleID, sheet, cell)
+			}
+		}
+		db.Close()
+	}
+}
+
+func TestMigrateSchemaNewerVersion(t *testing.T) {
+	old, path := openAtVersion(t, 0)
+	if _, err := old.Exec("INSERT INTO schema_version (version) VALUES (?)", migrations[len(migrations)-1].version+1); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+	if db, err := openDatabase(path); err == nil {
+		db.Close()
+		t.Error("数据库版本高于程序支持的版本时应当报错")
+	}
+}
+
+func TestDatabaseTriggers(t *testing.T) {
+	db, err := openDatabase(filepath.Join(t.TempDir(), "output.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	res, err := db.Exec("INSERT INTO detection_results (file_path, file_name, md5, detect_time) VALUES ('/a.txt', 'a.txt', 'm', '2024-01-01')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID, _ := res.LastInsertId()
+	if _, err := db.Exec("INSERT INTO matches (file_id, rule_id) VALUES (?, 'phone')", fileID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO detection_events (event_time, event_type, file_path, source) VALUES ('2024-01-01', 'sensitive', '/a.txt', 'full_scan')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除检测结果时一并删除命中明细
+	if _, err := db.Exec("DELETE FROM detection_results WHERE id = ?", fileID); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM matches").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("删除检测结果后还剩 %d 条命中明细", count)
+	}
+
+	// 检测事件只允许追加
+	for _, stmt := range []string{
+		"UPDATE detection_events SET event_type = 'scan'",
+		"DELETE FROM detection_events",
+	} {
+		if _, err := db.Exec(stmt); err == nil {
+			t.Errorf("%s 应当被触发器拒绝", stmt)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// OpenDocument 各部分的 XML 命名空间
+const (
+	odfTextNS  = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odfTableNS = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odfDrawNS  = "urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"
+)
+
+// readOdf 读取 OpenDocument 文档（odt、ods、odp）。与 readDocx、readPptx 一样解压后解析
+// content.xml：文字处理文档提取段落文本，电子表格的命中位置精确到工作表和单元格，
+// 演示文稿的命中位置精确到幻灯片
+func readOdf(path string, ctype ContentType) (io.Reader, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开%s文件失败: %v", ctype, err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != "content.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("打开content.xml失败: %v", err)
+		}
+		defer rc.Close()
+
+		p := &odfParser{spreadsheet: ctype == TypeOds}
+		if err := p.parse(xml.NewDecoder(rc)); err != nil {
+			return nil, fmt.Errorf("解析content.xml失败: %v", err)
+		}
+		return p.content.Reader(), nil
+	}
+	return nil, fmt.Errorf("不是有效的%s文件: %s", ctype, path)
+}
+
+// odfMaxSpaces text:s 最多展开的空格数，c 属性来自文件内容，不加限制时一个很大的值就会耗尽内存
+const odfMaxSpaces = 1024
+
+// odfMaxRepeat 行或单元格重复次数的上限，与电子表格的最大行数相同，防止坐标累加溢出
+const odfMaxRepeat = 1 << 20
+
+// odfParser 流式解析 content.xml
+type odfParser struct {
+	content     segmentBuilder
+	spreadsheet bool // 电子表格中的表格是工作表，记录单元格位置
+
+	paragraphs int // 当前所在的段落层数，只有段落内的文本才会被提取
+	skip       int // 当前所在的需要跳过的元素层数，如修订记录
+
+	sheet     string
+	row, col  int
+	rowRepeat int
+	colRepeat int
+	inCell    bool
+	cellValue string    // 单元格的 office:value，单元格没有文本时使用
+	cellMark  *Location // 单元格的位置，写入第一段文本时标记
+	cellText  bool      // 单元格是否已经写入文本
+
+	slide int
+}
+
+func (p *odfParser) parse(decoder *xml.Decoder) error {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			p.start(t)
+		case xml.EndElement:
+			p.end(t)
+		case xml.CharData:
+			if p.skip == 0 && p.paragraphs > 0 {
+				p.write(string(t))
+			}
+		}
+	}
+}
+
+func (p *odfParser) start(t xml.StartElement) {
+	if p.skip > 0 {
+		p.skip++
+		return
+	}
+
+	switch t.Name.Space {
+	case odfTextNS:
+		switch t.Name.Local {
+		case "tracked-changes", "note-citation":
+			// 修订记录中是已删除的文本，脚注编号不是正文
+			p.skip = 1
+		case "p", "h":
+			p.paragraphs++
+		case "note":
+			// 脚注内容嵌在正文段落中，与前面的文本分开
+			p.write(" ")
+		case "s":
+			n, _ := strconv.Atoi(odfAttr(t, "c"))
+			if n < 1 {
+				n = 1
+			}
+			if n > odfMaxSpaces {
+				n = odfMaxSpaces
+			}
+			p.write(strings.Repeat(" ", n))
+		case "tab":
+			p.write("\t")
+		case "line-break":
+			p.write("\n")
+		}
+
+	case odfTableNS:
+		if !p.spreadsheet {
+			return
+		}
+		switch t.Name.Local {
+		case "table":
+			p.sheet = odfAttr(t, "name")
+			p.row = 0
+		case "table-row":
+			p.col = 0
+			p.rowRepeat = odfRepeat(t, "number-rows-repeated")
+		case "table-cell", "covered-table-cell":
+			p.inCell = true
+			p.colRepeat = odfRepeat(t, "number-columns-repeated")
+			p.cellValue = odfAttr(t, "value")
+			cellName, _ := excelize.CoordinatesToCellName(p.col+1, p.row+1)
+			p.cellMark = &Location{Sheet: p.sheet, Cell: cellName}
+			p.cellText = false
+		}
+
+	case odfDrawNS:
+		if t.Name.Local == "page" {
+			p.slide++
+			p.content.Mark(Location{Slide: p.slide})
+		}
+	}
+}
+
+func (p *odfParser) end(t xml.EndElement) {
+	if p.skip > 0 {
+		p.skip--
+		return
+	}
+
+	switch t.Name.Space {
+	case odfTextNS:
+		if t.Name.Local == "p" || t.Name.Local == "h" {
+			p.paragraphs--
+			// 单元格中的多个段落用空格分隔，保持每行一条记录
+			if p.inCell {
+				if p.cellText {
+					p.write(" ")
+				}
+			} else {
+				p.write("\n")
+			}
+		}
+
+	case odfTableNS:
+		if !p.spreadsheet {
+			return
+		}
+		switch t.Name.Local {
+		case "table":
+			p.content.WriteString("\n")
+		case "table-row":
+			p.row += p.rowRepeat
+			p.content.WriteString("\n")
+		case "table-cell", "covered-table-cell":
+			if !p.cellText && p.cellValue != "" {
+				p.write(p.cellValue)
+			}
+			if p.cellText {
+				p.content.WriteString("\t")
+			}
+			p.inCell = false
+			p.cellMark = nil
+			p.col += p.colRepeat
+		}
+	}
+}
+
+// write 写入文本，单元格中的第一段文本之前标记单元格位置
+func (p *odfParser) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.inCell && p.cellMark != nil {
+		p.content.Mark(*p.cellMark)
+		p.cellMark = nil
+		p.cellText = true
+	}
+	p.content.WriteString(s)
+}
+
+// odfAttr 返回元素的属性值，按本地名称匹配
+func odfAttr(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odfRepeat 返回行或单元格的重复次数，默认 1。重复次数只用于推进行列坐标，
+// 单元格内容只写入一次，因此很大的值（如表格末尾重复上百万次的空行）不会放大输出
+func odfRepeat(t xml.StartElement, local string) int {
+	n, err := strconv.Atoi(odfAttr(t, local))
+	if err != nil || n < 1 {
+		return 1
+	}
+	if n > odfMaxRepeat {
+		return odfMaxRepeat
+	}
+	return n
+}
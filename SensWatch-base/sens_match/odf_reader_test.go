@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// readLocated 读取读取器的全部文本，返回 substr 首次出现处的位置，substr 不存在时测试失败
+func readLocated(t *testing.T, r io.Reader, substr string) (string, *Location) {
+	t.Helper()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	i := strings.Index(text, substr)
+	if i < 0 {
+		t.Fatalf("文本中没有 %q:\n%s", substr, text)
+	}
+	locator, ok := r.(Locator)
+	if !ok {
+		return text, nil
+	}
+	return text, locator.Locate(int64(i))
+}
+
+// odfContent 生成带有常用命名空间声明的 content.xml
+func odfContent(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+ xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+ xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+ xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"
+ xmlns:presentation="urn:oasis:names:tc:opendocument:xmlns:presentation:1.0">
+<office:body>` + body + `</office:body></office:document-content>`
+}
+
+func TestReadOdt(t *testing.T) {
+	path := writeZip(t, t.TempDir(), "a.odt",
+		"mimetype", "application/vnd.oasis.opendocument.text",
+		"content.xml", odfContent(`<office:text>
+<text:tracked-changes><text:changed-region><text:deletion><text:p>已删除13900000000</text:p></text:deletion></text:changed-region></text:tracked-changes>
+<text:h>标题</text:h>
+<text:p>电话<text:s text:c="2"/>13812345678<text:note><text:note-citation>1</text:note-citation><text:note-body><text:p>脚注内容</text:p></text:note-body></text:note></text:p>
+<text:p>很多空格<text:s text:c="100000000"/>结束</text:p>
+</office:text>`))
+
+	r, err := readOdf(path, TypeOdt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := readLocated(t, r, "电话  13812345678 脚注内容")
+	if strings.Contains(text, "13900000000") {
+		t.Errorf("修订记录中已删除的文本不应提取: %q", text)
+	}
+	spaces := strings.TrimSuffix(text[strings.Index(text, "很多空格")+len("很多空格"):], "结束\n")
+	if len(spaces) != odfMaxSpaces {
+		t.Errorf("text:s 展开了 %d 个空格，期望不超过 %d", len(spaces), odfMaxSpaces)
+	}
+}
+
+func TestReadOds(t *testing.T) {
+	path := writeZip(t, t.TempDir(), "a.ods",
+		"mimetype", "application/vnd.oasis.opendocument.spreadsheet",
+		"content.xml", odfContent(`<office:spreadsheet><table:table table:name="员工">
+<table:table-row table:number-rows-repeated="3"><table:table-cell/></table:table-row>
+<table:table-row>
+<table:table-cell table:number-columns-repeated="2"/>
+<table:table-cell><text:p>13812345678</text:p></table:table-cell>
+<table:table-cell office:value="13612345678"/>
+</table:table-row>
+<table:table-row table:number-rows-repeated="1048000"><table:table-cell table:number-columns-repeated="16000"/></table:table-row>
+<table:table-row><table:table-cell><text:p>末尾</text:p></table:table-cell></table:table-row>
+</table:table></office:spreadsheet>`))
+
+	r, err := readOdf(path, TypeOds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重复的行和单元格只推进坐标，不放大输出
+	if len(data) > 1024 {
+		t.Errorf("输出 %d 字节，重复的行或单元格被展开了", len(data))
+	}
+	tests := []struct {
+		text string
+		want Location
+	}{
+		{"13812345678", Location{Sheet: "员工", Cell: "C4"}},
+		{"13612345678", Location{Sheet: "员工", Cell: "D4"}},
+		{"末尾", Location{Sheet: "员工", Cell: "A1048005"}},
+	}
+	for _, tt := range tests {
+		i := strings.Index(string(data), tt.text)
+		if i < 0 {
+			t.Fatalf("输出中没有 %s: %q", tt.text, data)
+		}
+		if got := r.(Locator).Locate(int64(i)); got == nil || *got != tt.want {
+			t.Errorf("%s 的位置为 %v，期望 %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadOdp(t *testing.T) {
+	path := writeZip(t, t.TempDir(), "a.odp",
+		"mimetype", "application/vnd.oasis.opendocument.presentation",
+		"content.xml", odfContent(`<office:presentation>
+<draw:page><draw:frame><draw:text-box><text:p>封面</text:p></draw:text-box></draw:frame></draw:page>
+<draw:page><draw:frame><draw:text-box><text:p>联系 13812345678</text:p></draw:text-box></draw:frame>
+<presentation:notes><draw:frame><draw:text-box><text:p>备注 a@example.com</text:p></draw:text-box></draw:frame></presentation:notes></draw:page>
+</office:presentation>`))
+
+	for _, tt := range []struct {
+		text  string
+		slide int
+	}{{"封面", 1}, {"13812345678", 2}, {"a@example.com", 2}} {
+		r, err := readOdf(path, TypeOdp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, loc := readLocated(t, r, tt.text); loc == nil || loc.Slide != tt.slide {
+			t.Errorf("%s 的位置为 %v，期望第 %d 张幻灯片", tt.text, loc, tt.slide)
+		}
+	}
+}
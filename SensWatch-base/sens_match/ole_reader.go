@@ -0,0 +1,698 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+	"github.com/richardlehane/msoleps"
+	"github.com/xuri/excelize/v2"
+)
+
+// oleDocument 从 Office 97-2003 复合文档中读取出的内容
+type oleDocument struct {
+	streams map[string][]byte // 根目录下需要的流
+	props   []string          // SummaryInformation 等属性集中的文本属性，如标题、作者
+}
+
+// openOLE 读取复合文档根目录下指定名称的流和属性集
+func openOLE(path string, names ...string) (*oleDocument, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	doc, err := mscfb.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析OLE复合文档失败: %v", err)
+	}
+
+	ole := &oleDocument{streams: make(map[string][]byte)}
+	props := msoleps.New()
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		if len(entry.Path) > 0 {
+			continue
+		}
+		if msoleps.IsMSOLEPS(entry.Initial) {
+			if props.Reset(doc) != nil {
+				continue
+			}
+			for _, prop := range props.Property {
+				if t := prop.Type(); t != "UnicodeString" && t != "CodeString" {
+					continue
+				}
+				if value := strings.TrimSpace(prop.String()); value != "" {
+					ole.props = append(ole.props, value)
+				}
+			}
+			continue
+		}
+		for _, name := range names {
+			if entry.Name == name {
+				data, err := io.ReadAll(entry)
+				if err != nil {
+					return nil, fmt.Errorf("读取%s流失败: %v", name, err)
+				}
+				ole.streams[name] = data
+			}
+		}
+	}
+	return ole, nil
+}
+
+// writeProps 把文档属性写在提取文本的开头，属性没有文档位置
+func (d *oleDocument) writeProps(content *segmentBuilder) {
+	for _, prop := range d.props {
+		content.WriteString(prop)
+		content.WriteString("\n")
+	}
+}
+
+// readDoc 读取 Word 97-2003 文档。正文、页眉页脚、脚注和批注的文本都按 Clx 中的
+// piece table 从 WordDocument 流中拼出，每一段是 UTF-16LE 或压缩的 8 位字符
+func readDoc(path string) (io.Reader, error) {
+	ole, err := openOLE(path, "WordDocument", "0Table", "1Table")
+	if err != nil {
+		return nil, err
+	}
+	wd := ole.streams["WordDocument"]
+	if len(wd) < 64 {
+		return nil, fmt.Errorf("不是有效的doc文件: %s", path)
+	}
+	if ident := binary.LittleEndian.Uint16(wd); ident != 0xA5EC {
+		return nil, fmt.Errorf("不支持Word 95及更早版本的doc文件: %s", path)
+	}
+	flags := binary.LittleEndian.Uint16(wd[0x0A:])
+	if flags&0x0100 != 0 {
+		return nil, fmt.Errorf("doc文件已加密: %s", path)
+	}
+	table := ole.streams["0Table"]
+	if flags&0x0200 != 0 {
+		table = ole.streams["1Table"]
+	}
+
+	// FIB 中 fibRgW、fibRgLw 的长度可变，逐段跳过后找到 fcClx/lcbClx
+	pos := 32
+	if pos+2 > len(wd) {
+		return nil, fmt.Errorf("doc文件头损坏: %s", path)
+	}
+	pos += 2 + 2*int(binary.LittleEndian.Uint16(wd[pos:]))
+	if pos+2 > len(wd) {
+		return nil, fmt.Errorf("doc文件头损坏: %s", path)
+	}
+	pos += 2 + 4*int(binary.LittleEndian.Uint16(wd[pos:]))
+	if pos+2 > len(wd) {
+		return nil, fmt.Errorf("doc文件头损坏: %s", path)
+	}
+	cbRgFcLcb := int(binary.LittleEndian.Uint16(wd[pos:]))
+	pos += 2
+	const clxIndex = 33 // fcClx 在 FibRgFcLcb97 中的序号
+	if cbRgFcLcb <= clxIndex || pos+clxIndex*8+8 > len(wd) {
+		return nil, fmt.Errorf("doc文件头损坏: %s", path)
+	}
+	fcClx := int(binary.LittleEndian.Uint32(wd[pos+clxIndex*8:]))
+	lcbClx := int(binary.LittleEndian.Uint32(wd[pos+clxIndex*8+4:]))
+	if lcbClx <= 0 || fcClx < 0 || fcClx > len(table) || lcbClx > len(table)-fcClx {
+		return nil, fmt.Errorf("doc文件缺少文本索引: %s", path)
+	}
+
+	pieces, err := parseClx(table[fcClx : fcClx+lcbClx])
+	if err != nil {
+		return nil, fmt.Errorf("解析doc文本索引失败: %v", err)
+	}
+
+	var text []rune
+	for _, p := range pieces {
+		n := int(p.cpEnd) - int(p.cpStart)
+		if n <= 0 {
+			continue
+		}
+		if p.compressed {
+			start := int(p.fc / 2)
+			if start+n > len(wd) {
+				return nil, fmt.Errorf("doc文本超出WordDocument流: %s", path)
+			}
+			for _, b := range wd[start : start+n] {
+				text = append(text, decodeCP1252(b))
+			}
+		} else {
+			start := int(p.fc)
+			if start+2*n > len(wd) {
+				return nil, fmt.Errorf("doc文本超出WordDocument流: %s", path)
+			}
+			text = append(text, decodeUTF16LE(wd[start:start+2*n])...)
+		}
+	}
+
+	var content segmentBuilder
+	ole.writeProps(&content)
+	content.WriteString(cleanWordText(text))
+	return content.Reader(), nil
+}
+
+// docPiece 表示 piece table 中的一段文本
+type docPiece struct {
+	cpStart, cpEnd uint32 // 字符位置范围
+	fc             uint32 // 在 WordDocument 流中的偏移，压缩文本为字节偏移的两倍
+	compressed     bool
+}
+
+// parseClx 解析 Clx：跳过开头的 Prc，读取 Pcdt 中的 PlcPcd
+func parseClx(clx []byte) ([]docPiece, error) {
+	pos := 0
+	for pos < len(clx) {
+		switch clx[pos] {
+		case 0x01:
+			if pos+3 > len(clx) {
+				return nil, fmt.Errorf("Prc 被截断")
+			}
+			// cbGrpprl 是有符号数，负数或超出 Clx 的长度会导致越界或死循环
+			cb := int(int16(binary.LittleEndian.Uint16(clx[pos+1:])))
+			if cb < 0 || cb > len(clx)-pos-3 {
+				return nil, fmt.Errorf("Prc 长度无效: %d", cb)
+			}
+			pos += 3 + cb
+		case 0x02:
+			if pos+5 > len(clx) {
+				return nil, fmt.Errorf("Pcdt 被截断")
+			}
+			lcb := int64(binary.LittleEndian.Uint32(clx[pos+1:]))
+			plc := clx[pos+5:]
+			if lcb < 4 || lcb > int64(len(plc)) || (lcb-4)%12 != 0 {
+				return nil, fmt.Errorf("PlcPcd 长度无效: %d", lcb)
+			}
+			n := int(lcb-4) / 12
+			pcds := plc[4*(n+1):]
+			pieces := make([]docPiece, n)
+			for i := range pieces {
+				fc := binary.LittleEndian.Uint32(pcds[8*i+2:])
+				pieces[i] = docPiece{
+					cpStart:    binary.LittleEndian.Uint32(plc[4*i:]),
+					cpEnd:      binary.LittleEndian.Uint32(plc[4*i+4:]),
+					fc:         fc &^ 0x40000000,
+					compressed: fc&0x40000000 != 0,
+				}
+			}
+			return pieces, nil
+		default:
+			return nil, fmt.Errorf("无效的 Clx 类型: %#x", clx[pos])
+		}
+	}
+	return nil, fmt.Errorf("缺少 Pcdt")
+}
+
+// cleanWordText 处理 Word 的特殊字符：段落、换行和分页符转为换行，单元格结束符转为制表符，
+// 丢弃域代码（0x13 与 0x14 之间）只保留域结果，丢弃图片、脚注引用等占位符
+func cleanWordText(text []rune) string {
+	var b strings.Builder
+	var fields []bool // 每层域当前是否处于域代码部分
+	for _, c := range text {
+		switch c {
+		case 0x13:
+			fields = append(fields, true)
+			continue
+		case 0x14:
+			if len(fields) > 0 {
+				fields[len(fields)-1] = false
+			}
+			continue
+		case 0x15:
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+			continue
+		}
+		// 外层域处于域代码部分时，嵌套在其中的域的结果也属于域代码
+		if inFieldCode(fields) {
+			continue
+		}
+		switch {
+		case c == '\r' || c == 0x0B || c == 0x0C || c == 0x0E:
+			b.WriteByte('\n')
+		case c == 0x07:
+			b.WriteByte('\t')
+		case c == 0x1E:
+			b.WriteByte('-')
+		case c == 0xA0:
+			b.WriteByte(' ')
+		case c < 0x20 && c != '\t':
+			// 图片、脚注引用、可选连字符等占位符
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// inFieldCode 返回是否有任意一层域处于域代码部分
+func inFieldCode(fields []bool) bool {
+	for _, code := range fields {
+		if code {
+			return true
+		}
+	}
+	return false
+}
+
+// readXls 读取 Excel 97-2003 工作簿（BIFF8）。逐条解析 Workbook 流中的记录，
+// 按工作表收集文本、数字和公式结果，命中位置精确到单元格
+func readXls(path string) (io.Reader, error) {
+	ole, err := openOLE(path, "Workbook", "Book")
+	if err != nil {
+		return nil, err
+	}
+	data := ole.streams["Workbook"]
+	if data == nil {
+		if ole.streams["Book"] != nil {
+			return nil, fmt.Errorf("不支持Excel 95及更早版本的xls文件: %s", path)
+		}
+		return nil, fmt.Errorf("不是有效的xls文件: %s", path)
+	}
+
+	type xlsCell struct {
+		row, col int
+		text     string
+	}
+	var (
+		sheetNames  []string
+		sheetOffset = make(map[int]int) // 工作表 BOF 记录在流中的偏移 -> 工作表序号
+		sheet       = -1                // 当前工作表，-1 表示工作簿全局部分
+		cells       = make(map[int][]xlsCell)
+		sst         []string
+		formula     *xlsCell // 等待 STRING 记录的字符串公式
+	)
+	addCell := func(row, col int, text string) {
+		if sheet >= 0 && text != "" {
+			cells[sheet] = append(cells[sheet], xlsCell{row: row, col: col, text: text})
+		}
+	}
+
+	for pos := 0; pos+4 <= len(data); {
+		typ := binary.LittleEndian.Uint16(data[pos:])
+		size := int(binary.LittleEndian.Uint16(data[pos+2:]))
+		start := pos
+		if pos+4+size > len(data) {
+			break
+		}
+		rec := data[pos+4 : pos+4+size]
+		pos += 4 + size
+
+		switch typ {
+		case 0x002F: // FILEPASS
+			if sheet < 0 {
+				return nil, fmt.Errorf("xls文件已加密: %s", path)
+			}
+		case 0x0809: // BOF
+			if i, ok := sheetOffset[start]; ok {
+				sheet = i
+			}
+		case 0x0085: // BOUNDSHEET
+			if len(rec) >= 8 {
+				sheetOffset[int(binary.LittleEndian.Uint32(rec))] = len(sheetNames)
+				name, _ := readXLString(rec[6:], int(rec[6]), 1)
+				sheetNames = append(sheetNames, name)
+			}
+		case 0x00FC: // SST，可能延续到其后的 CONTINUE 记录中
+			chunks := [][]byte{rec}
+			for pos+4 <= len(data) && binary.LittleEndian.Uint16(data[pos:]) == 0x003C {
+				n := int(binary.LittleEndian.Uint16(data[pos+2:]))
+				if pos+4+n > len(data) {
+					break
+				}
+				chunks = append(chunks, data[pos+4:pos+4+n])
+				pos += 4 + n
+			}
+			sst = parseSST(chunks)
+		case 0x00FD: // LABELSST
+			if len(rec) >= 10 {
+				if i := int(binary.LittleEndian.Uint32(rec[6:])); i < len(sst) {
+					row, col := xlsRowCol(rec)
+					addCell(row, col, sst[i])
+				}
+			}
+		case 0x0204: // LABEL
+			if len(rec) >= 9 {
+				text, _ := readXLString(rec[6:], int(binary.LittleEndian.Uint16(rec[6:])), 2)
+				row, col := xlsRowCol(rec)
+				addCell(row, col, text)
+			}
+		case 0x0203: // NUMBER
+			if len(rec) >= 14 {
+				row, col := xlsRowCol(rec)
+				addCell(row, col, formatXlsNumber(math.Float64frombits(binary.LittleEndian.Uint64(rec[6:]))))
+			}
+		case 0x027E: // RK
+			if len(rec) >= 10 {
+				row, col := xlsRowCol(rec)
+				addCell(row, col, formatXlsNumber(decodeRK(binary.LittleEndian.Uint32(rec[6:]))))
+			}
+		case 0x00BD: // MULRK
+			if len(rec) >= 6 {
+				row, col := xlsRowCol(rec)
+				for i := 4; i+6 <= len(rec)-2; i += 6 {
+					addCell(row, col, formatXlsNumber(decodeRK(binary.LittleEndian.Uint32(rec[i+2:]))))
+					col++
+				}
+			}
+		case 0x0006: // FORMULA，结果为字符串时由紧随其后的 STRING 记录给出
+			if len(rec) >= 14 {
+				row, col := xlsRowCol(rec)
+				if binary.LittleEndian.Uint16(rec[12:]) != 0xFFFF {
+					addCell(row, col, formatXlsNumber(math.Float64frombits(binary.LittleEndian.Uint64(rec[6:]))))
+				} else if rec[6] == 0 {
+					formula = &xlsCell{row: row, col: col}
+				}
+			}
+		case 0x0207: // STRING
+			if formula != nil && len(rec) >= 3 {
+				text, _ := readXLString(rec, int(binary.LittleEndian.Uint16(rec)), 2)
+				addCell(formula.row, formula.col, text)
+			}
+			formula = nil
+		}
+	}
+
+	var content segmentBuilder
+	ole.writeProps(&content)
+	for i, name := range sheetNames {
+		sheetCells := cells[i]
+		sort.SliceStable(sheetCells, func(a, b int) bool {
+			if sheetCells[a].row != sheetCells[b].row {
+				return sheetCells[a].row < sheetCells[b].row
+			}
+			return sheetCells[a].col < sheetCells[b].col
+		})
+		for j, cell := range sheetCells {
+			if j > 0 && cell.row != sheetCells[j-1].row {
+				content.WriteString("\n")
+			}
+			cellName, _ := excelize.CoordinatesToCellName(cell.col+1, cell.row+1)
+			content.Mark(Location{Sheet: name, Cell: cellName})
+			content.WriteString(cell.text)
+			content.WriteString("\t")
+		}
+		content.WriteString("\n")
+	}
+	return content.Reader(), nil
+}
+
+// xlsRowCol 返回单元格记录开头的行号和列号（从 0 开始）
+func xlsRowCol(rec []byte) (int, int) {
+	return int(binary.LittleEndian.Uint16(rec)), int(binary.LittleEndian.Uint16(rec[2:]))
+}
+
+// readXLString 读取 BIFF8 字符串：lenSize 字节的字符数、1 字节选项和字符数据，
+// 选项最低位为 1 时每个字符占 2 字节。返回字符串和占用的字节数
+func readXLString(b []byte, cch, lenSize int) (string, int) {
+	if len(b) < lenSize+1 {
+		return "", len(b)
+	}
+	flags := b[lenSize]
+	pos := lenSize + 1
+	if flags&0x08 != 0 {
+		pos += 2 // 富文本格式数量
+	}
+	if flags&0x04 != 0 {
+		pos += 4 // 东亚文字扩展信息长度
+	}
+	if flags&0x01 != 0 {
+		end := pos + 2*cch
+		if end > len(b) {
+			end = pos + (len(b)-pos)/2*2
+		}
+		if end < pos {
+			return "", len(b)
+		}
+		return string(decodeUTF16LE(b[pos:end])), end
+	}
+	end := pos + cch
+	if end > len(b) {
+		end = len(b)
+	}
+	if end < pos {
+		return "", len(b)
+	}
+	return decodeLatin1(b[pos:end]), end
+}
+
+// parseSST 解析共享字符串表。字符串可能跨越 CONTINUE 记录，
+// 字符数据在记录边界处断开时，下一条记录以新的选项字节开头
+func parseSST(chunks [][]byte) []string {
+	r := &sstReader{chunks: chunks}
+	if _, ok := r.uint32(); !ok {
+		return nil
+	}
+	unique, ok := r.uint32()
+	if !ok {
+		return nil
+	}
+
+	var strs []string
+	for i := uint32(0); i < unique; i++ {
+		cch, ok1 := r.uint16()
+		flags, ok2 := r.byte()
+		if !ok1 || !ok2 {
+			break
+		}
+		var runs, ext int
+		if flags&0x08 != 0 {
+			n, _ := r.uint16()
+			runs = int(n)
+		}
+		if flags&0x04 != 0 {
+			n, _ := r.uint32()
+			ext = int(n)
+		}
+
+		high := flags&0x01 != 0
+		chars := make([]uint16, 0, cch)
+		for len(chars) < int(cch) {
+			if r.atChunkEnd() {
+				// 字符数据在记录边界处断开，下一条记录以新的选项字节开头
+				if !r.next() {
+					break
+				}
+				f, ok := r.byte()
+				if !ok {
+					break
+				}
+				high = f&0x01 != 0
+			}
+			if high {
+				c, ok := r.uint16()
+				if !ok {
+					break
+				}
+				chars = append(chars, c)
+			} else {
+				c, ok := r.byte()
+				if !ok {
+					break
+				}
+				chars = append(chars, uint16(c))
+			}
+		}
+		strs = append(strs, string(utf16.Decode(chars)))
+		r.skip(4*runs + ext)
+	}
+	return strs
+}
+
+// sstReader 按顺序读取分布在多条记录中的共享字符串表
+type sstReader struct {
+	chunks [][]byte
+	ci     int // 当前记录
+	pos    int // 在当前记录中的位置
+}
+
+func (r *sstReader) atChunkEnd() bool {
+	return r.ci < len(r.chunks) && r.pos >= len(r.chunks[r.ci])
+}
+
+func (r *sstReader) next() bool {
+	r.ci++
+	r.pos = 0
+	return r.ci < len(r.chunks)
+}
+
+func (r *sstReader) byte() (byte, bool) {
+	for r.atChunkEnd() {
+		if !r.next() {
+			return 0, false
+		}
+	}
+	if r.ci >= len(r.chunks) {
+		return 0, false
+	}
+	b := r.chunks[r.ci][r.pos]
+	r.pos++
+	return b, true
+}
+
+func (r *sstReader) uint16() (uint16, bool) {
+	lo, ok1 := r.byte()
+	hi, ok2 := r.byte()
+	return uint16(lo) | uint16(hi)<<8, ok1 && ok2
+}
+
+func (r *sstReader) uint32() (uint32, bool) {
+	lo, ok1 := r.uint16()
+	hi, ok2 := r.uint16()
+	return uint32(lo) | uint32(hi)<<16, ok1 && ok2
+}
+
+func (r *sstReader) skip(n int) {
+	for ; n > 0; n-- {
+		if _, ok := r.byte(); !ok {
+			return
+		}
+	}
+}
+
+// decodeRK 解码 RK 格式的数字
+func decodeRK(rk uint32) float64 {
+	var v float64
+	if rk&0x02 != 0 {
+		v = float64(int32(rk) >> 2)
+	} else {
+		v = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		v /= 100
+	}
+	return v
+}
+
+// formatXlsNumber 把数字格式化为不带指数的文本，保证手机号、证件号等长数字能被规则匹配
+func formatXlsNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// readPpt 读取 PowerPoint 97-2003 演示文稿。遍历 PowerPoint Document 流中的记录，
+// 提取文本原子；幻灯片列表和幻灯片容器中的文本带上幻灯片编号，母版中的模板文本不提取
+func readPpt(path string) (io.Reader, error) {
+	ole, err := openOLE(path, "PowerPoint Document")
+	if err != nil {
+		return nil, err
+	}
+	data := ole.streams["PowerPoint Document"]
+	if data == nil {
+		return nil, fmt.Errorf("不是有效的ppt文件: %s", path)
+	}
+
+	var content segmentBuilder
+	ole.writeProps(&content)
+	w := &pptWalker{content: &content}
+	w.walk(data, 0)
+	return content.Reader(), nil
+}
+
+// pptWalker 遍历 PowerPoint 记录树，记录当前所在的幻灯片
+type pptWalker struct {
+	content    *segmentBuilder
+	slide      int  // 当前文本所属的幻灯片，0 表示未知（如备注）
+	slides     int  // 已经遇到的幻灯片容器数量
+	inList     bool // 是否在幻灯片的 SlideListWithText 中
+	listSlides int  // SlideListWithText 中已经遇到的幻灯片数量
+}
+
+// pptMaxDepth 记录嵌套的最大深度，防止损坏的文件导致过深的递归
+const pptMaxDepth = 32
+
+func (w *pptWalker) walk(b []byte, depth int) {
+	if depth > pptMaxDepth {
+		return
+	}
+	for pos := 0; pos+8 <= len(b); {
+		verInst := binary.LittleEndian.Uint16(b[pos:])
+		typ := binary.LittleEndian.Uint16(b[pos+2:])
+		size := int(binary.LittleEndian.Uint32(b[pos+4:]))
+		end := pos + 8 + size
+		if size < 0 || end > len(b) || end < pos {
+			end = len(b)
+		}
+		body := b[pos+8 : end]
+		pos = end
+
+		ver, inst := verInst&0x0F, verInst>>4
+		switch {
+		case typ == 0x03F8 || (typ == 0x0FF0 && inst == 1):
+			// 母版和母版的文本列表只有模板文本
+		case typ == 0x0FF0: // SlideListWithText，instance 0 为幻灯片，2 为备注
+			saved := *w
+			w.inList, w.listSlides, w.slide = inst == 0, 0, 0
+			w.walk(body, depth+1)
+			w.inList, w.listSlides, w.slide = saved.inList, saved.listSlides, saved.slide
+		case typ == 0x03F3: // SlidePersistAtom，其后的文本属于下一张幻灯片
+			if w.inList {
+				w.listSlides++
+				w.slide = w.listSlides
+			}
+		case typ == 0x03EE: // Slide
+			w.slides++
+			saved := w.slide
+			w.slide = w.slides
+			w.walk(body, depth+1)
+			w.slide = saved
+		case typ == 0x03F0: // Notes
+			saved := w.slide
+			w.slide = 0
+			w.walk(body, depth+1)
+			w.slide = saved
+		case typ == 0x0FA0: // TextCharsAtom
+			w.emit(string(decodeUTF16LE(body)))
+		case typ == 0x0FA8: // TextBytesAtom
+			w.emit(decodeLatin1(body))
+		case ver == 0x0F:
+			w.walk(body, depth+1)
+		}
+	}
+}
+
+// emit 写入一段文本，段落分隔符和换行符转为换行
+func (w *pptWalker) emit(text string) {
+	text = strings.NewReplacer("\r", "\n", "\v", "\n").Replace(text)
+	w.content.Mark(Location{Slide: w.slide})
+	w.content.WriteString(text)
+	w.content.WriteString("\n")
+}
+
+// decodeUTF16LE 解码 UTF-16LE 字节，末尾多余的单个字节被忽略
+func decodeUTF16LE(b []byte) []rune {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return utf16.Decode(units)
+}
+
+// decodeLatin1 解码 BIFF8 和 PowerPoint 中压缩存储的字符（UTF-16 的低字节）
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// cp1252High Windows-1252 中 0x80-0x9F 对应的字符，Word 压缩文本使用该编码
+var cp1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// decodeCP1252 解码 Word 压缩文本中的一个字节
+func decodeCP1252(b byte) rune {
+	if b >= 0x80 && b < 0xA0 {
+		return cp1252High[b-0x80]
+	}
+	return rune(b)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// pcdt 生成包含给定 piece 的 Pcdt：CP 数组之后是每个 piece 8 字节的 Pcd
+func pcdt(cps []uint32, fcs []uint32) []byte {
+	var plc []byte
+	for _, cp := range cps {
+		plc = binary.LittleEndian.AppendUint32(plc, cp)
+	}
+	for _, fc := range fcs {
+		plc = append(plc, 0, 0)
+		plc = binary.LittleEndian.AppendUint32(plc, fc)
+		plc = append(plc, 0, 0)
+	}
+	b := []byte{0x02}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(plc)))
+	return append(b, plc...)
+}
+
+func TestParseClx(t *testing.T) {
+	valid := pcdt([]uint32{0, 10, 25}, []uint32{0x40000800, 0x1000})
+	want := []docPiece{
+		{cpStart: 0, cpEnd: 10, fc: 0x800, compressed: true},
+		{cpStart: 10, cpEnd: 25, fc: 0x1000},
+	}
+
+	tests := []struct {
+		name    string
+		clx     []byte
+		want    []docPiece
+		wantErr bool
+	}{
+		{"只有 Pcdt", valid, want, false},
+		{"跳过 Prc", append([]byte{0x01, 0x02, 0x00, 0xAA, 0xBB}, valid...), want, false},
+		{"空的 Prc", append([]byte{0x01, 0x00, 0x00}, valid...), want, false},
+		{"cbGrpprl 为负数", []byte{0x01, 0xF0, 0xFF, 0x00, 0x00}, nil, true},
+		{"cbGrpprl 为 -3 会原地循环", []byte{0x01, 0xFD, 0xFF, 0x02}, nil, true},
+		{"cbGrpprl 超出 Clx", []byte{0x01, 0x10, 0x00, 0x00}, nil, true},
+		{"Prc 被截断", []byte{0x01, 0x00}, nil, true},
+		{"Pcdt 被截断", []byte{0x02, 0x10, 0x00}, nil, true},
+		{"lcb 超出 Clx", []byte{0x02, 0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}, nil, true},
+		{"lcb 小于 4", []byte{0x02, 0x02, 0x00, 0x00, 0x00, 0, 0}, nil, true},
+		{"lcb 不是完整的 PlcPcd", append([]byte{0x02, 0x0A, 0x00, 0x00, 0x00}, make([]byte, 10)...), nil, true},
+		{"无效的类型", []byte{0x03}, nil, true},
+		{"缺少 Pcdt", []byte{0x01, 0x00, 0x00}, nil, true},
+		{"空", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClx(tt.clx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClx() err = %v，期望出错: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseClx() = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanWordText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"段落和单元格", "姓名\a张三\a\r下一段", "姓名\t张三\t\n下一段"},
+		{"只保留域结果", "电话\x13 HYPERLINK \"tel:1\" \x1413812345678\x15。", "电话13812345678。"},
+		{"嵌套的域", "\x13 IF \x13 PAGE \x141\x15 \x14结果\x15", "结果"},
+		{"占位符和不可断连字符", "图\x01片 说明\x1e号", "图片 说明-号"},
+	}
+	for _, tt := range tests {
+		if got := cleanWordText([]rune(tt.in)); got != tt.want {
+			t.Errorf("%s: cleanWordText() = %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRK(t *testing.T) {
+	tests := []struct {
+		rk   uint32
+		want string
+	}{
+		{123<<2 | 0x02, "123"},
+		{12345<<2 | 0x03, "123.45"},
+		{0x3FF80000, "1.5"},
+		{0xFFFFFFE6, "-7"}, // -7 左移两位后置整数标志
+	}
+	for _, tt := range tests {
+		if got := formatXlsNumber(decodeRK(tt.rk)); got != tt.want {
+			t.Errorf("decodeRK(%#x) = %s，期望 %s", tt.rk, got, tt.want)
+		}
+	}
+}
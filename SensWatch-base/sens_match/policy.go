@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPolicyYAML 内置的默认策略包
+//
+//go:embed policies/default.yaml
+var defaultPolicyYAML []byte
+
+// RuleDef 表示策略文件中的一条规则定义
+type RuleDef struct {
+	ID        string       `yaml:"id" json:"id"`
+	Name      string       `yaml:"name" json:"name"`
+	Number    int          `yaml:"number" json:"number"`
+	Pattern   string       `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Group     int          `yaml:"group,omitempty" json:"group,omitempty"`         // 取第几个捕获组作为命中值，0 表示整个匹配
+	Validator string       `yaml:"validator,omitempty" json:"validator,omitempty"` // 可选的校验器名称
+	Detector  string       `yaml:"detector,omitempty" json:"detector,omitempty"`   // 内置检测器名称，与 pattern 二选一
+	Severity  Severity     `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Enabled   *bool        `yaml:"enabled,omitempty" json:"enabled,omitempty"` // 未设置时默认启用
+	MinHits   int          `yaml:"min_hits,omitempty" json:"min_hits,omitempty"`
+	Mask      MaskStrategy `yaml:"mask,omitempty" json:"mask,omitempty"` // 落盘前的打码方式，默认 partial
+}
+
+// MaskingConfig 表示结果落盘时的打码设置
+type MaskingConfig struct {
+	Mode StoreMode `yaml:"mode,omitempty" json:"mode,omitempty"` // 默认 masked
+}
+
+// Policy 表示一份检测策略
+type Policy struct {
+	Version string        `yaml:"version" json:"version"`
+	Masking MaskingConfig `yaml:"masking,omitempty" json:"masking,omitempty"`
+	Rules   []RuleDef     `yaml:"rules" json:"rules"`
+}
+
+// patternCache 按正则源码缓存编译结果。*regexp.Regexp 可以被多个 goroutine
+// 并发使用，因此所有 SensMatch 实例共享同一份编译结果
+var patternCache sync.Map
+
+// compilePattern 编译正则表达式，已编译过的直接从缓存返回
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := patternCache.LoadOrStore(pattern, re)
+	return actual.(*regexp.Regexp), nil
+}
+
+// Validator 对正则命中的候选值做二次校验，返回规范化后的值和是否有效
+type Validator func(value string) (string, bool)
+
+// validators 策略文件中可引用的校验器
+var validators = map[string]Validator{
+	"luhn":         validateBankCard,
+	"ipv6":         validateIPv6,
+	"telephone":    validateTelephone,
+	"jdbc":         validateJDBC,
+	"organization": validateOrganization,
+	"business":     validateBusiness,
+	"credit":       validateCredit,
+}
+
+// typeMismatchDetector 文件级检测器，不扫描文本，由 FileProcessor 在文件扩展名与实际内容不符时产生命中
+const typeMismatchDetector = "type_mismatch"
+
+// detectors 策略文件中可引用的非正则检测器
+var detectors = map[string]func(s *SensMatch) func(string) []Match{
+	"address_name":       func(s *SensMatch) func(string) []Match { return s.addressNameChecker.Find },
+	typeMismatchDetector: func(s *SensMatch) func(string) []Match { return func(string) []Match { return nil } },
+}
+
+// policyRule 根据 RuleDef 编译得到的规则
+type policyRule struct {
+	def       RuleDef
+	re        *regexp.Regexp
+	validator Validator
+	detector  func(string) []Match
+}
+
+func (r *policyRule) ID() string         { return r.def.ID }
+func (r *policyRule) Name() string       { return r.def.Name }
+func (r *policyRule) Number() int        { return r.def.Number }
+func (r *policyRule) Severity() Severity { return r.def.Severity }
+func (r *policyRule) MinHits() int       { return r.def.MinHits }
+func (r *policyRule) Mask() MaskStrategy { return r.def.Mask }
+func (r *policyRule) Detector() string   { return r.def.Detector }
+
+// Find 查找文本中的所有命中，Offset 为命中值在 text 中的字节偏移
+func (r *policyRule) Find(text string) []Match {
+	var candidates []Match
+	switch {
+	case r.detector != nil:
+		candidates = r.detector(text)
+	case r.def.Group == 0:
+		for _, loc := range r.re.FindAllStringIndex(text, -1) {
+			candidates = append(candidates, Match{Value: text[loc[0]:loc[1]], Offset: int64(loc[0])})
+		}
+	default:
+		for _, loc := range r.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*r.def.Group], loc[2*r.def.Group+1]
+			if start < 0 || start == end {
+				continue
+			}
+			candidates = append(candidates, Match{Value: text[start:end], Offset: int64(start)})
+		}
+	}
+
+	var matches []Match
+	for _, m := range candidates {
+		if r.validator != nil {
+			normalized, ok := r.validator(m.Value)
+			if !ok {
+				continue
+			}
+			m.Value = normalized
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// compileRule 校验规则定义并编译
+func compileRule(def RuleDef, s *SensMatch) (*policyRule, error) {
+	if def.ID == "" {
+		return nil, fmt.Errorf("规则缺少 id")
+	}
+	if def.Number <= 0 {
+		return nil, fmt.Errorf("规则 %s 的编号无效: %d", def.ID, def.Number)
+	}
+	if def.Name == "" {
+		def.Name = def.ID
+	}
+	switch def.Severity {
+	case "":
+		def.Severity = SeverityMedium
+	case SeverityLow, SeverityMedium, SeverityHigh:
+	default:
+		return nil, fmt.Errorf("规则 %s 的敏感等级无效: %s", def.ID, def.Severity)
+	}
+	if def.MinHits <= 0 {
+		def.MinHits = 1
+	}
+	switch def.Mask {
+	case "":
+		def.Mask = MaskPartial
+	case MaskPartial, MaskHMAC, MaskRedact:
+	default:
+		return nil, fmt.Errorf("规则 %s 的打码方式无效: %s", def.ID, def.Mask)
+	}
+
+	rule := &policyRule{def: def}
+	if def.Validator != "" {
+		v, ok := validators[def.Validator]
+		if !ok {
+			return nil, fmt.Errorf("规则 %s 引用了未知的校验器: %s", def.ID, def.Validator)
+		}
+		rule.validator = v
+	}
+
+	switch {
+	case def.Pattern != "" && def.Detector != "":
+		return nil, fmt.Errorf("规则 %s 不能同时设置 pattern 和 detector", def.ID)
+	case def.Detector != "":
+		d, ok := detectors[def.Detector]
+		if !ok {
+			return nil, fmt.Errorf("规则 %s 引用了未知的检测器: %s", def.ID, def.Detector)
+		}
+		rule.detector = d(s)
+	case def.Pattern != "":
+		re, err := compilePattern(def.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("规则 %s 的正则表达式无效: %v", def.ID, err)
+		}
+		if def.Group < 0 || def.Group > re.NumSubexp() {
+			return nil, fmt.Errorf("规则 %s 的捕获组 %d 不存在", def.ID, def.Group)
+		}
+		rule.re = re
+	default:
+		return nil, fmt.Errorf("规则 %s 缺少 pattern 或 detector", def.ID)
+	}
+	return rule, nil
+}
+
+// Apply 编译策略中的所有规则并注册到注册表
+func (p *Policy) Apply(registry *RuleRegistry, s *SensMatch) error {
+	for _, def := range p.Rules {
+		rule, err := compileRule(def, s)
+		if err != nil {
+			return err
+		}
+		if err := registry.Register(rule); err != nil {
+			return err
+		}
+		if def.Enabled != nil && !*def.Enabled {
+			registry.SetEnabled(def.ID, false)
+		}
+	}
+	return nil
+}
+
+// RuleSetVersion 返回规则集版本：策略版本号加上规则和打码设置的摘要。
+// 修改了规则但忘记修改版本号时，版本同样会变化
+func (p *Policy) RuleSetVersion() string {
+	data, _ := json.Marshal(struct {
+		Masking MaskingConfig
+		Rules   []RuleDef
+	}{p.Masking, p.Rules})
+	sum := sha256.Sum256(data)
+	return p.Version + "-" + hex.EncodeToString(sum[:4])
+}
+
+// ParsePolicy 解析策略内容，isJSON 为 false 时按 YAML 解析
+func ParsePolicy(data []byte, isJSON bool) (*Policy, error) {
+	var policy Policy
+	var err error
+	if isJSON {
+		err = json.Unmarshal(data, &policy)
+	} else {
+		err = yaml.Unmarshal(data, &policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析策略失败: %v", err)
+	}
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("策略中没有任何规则")
+	}
+	return &policy, nil
+}
+
+// LoadPolicyFile 从 YAML 或 JSON 文件加载策略
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %v", err)
+	}
+	return ParsePolicy(data, strings.ToLower(filepath.Ext(path)) == ".json")
+}
+
+// LoadPolicyOrDefault 策略文件存在时加载它，否则使用内置默认策略
+func LoadPolicyOrDefault(path string) (*Policy, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return DefaultPolicy(), nil
+	}
+	return LoadPolicyFile(path)
+}
+
+var (
+	defaultPolicyOnce sync.Once
+	defaultPolicy     *Policy
+)
+
+// DefaultPolicy 返回内置的默认策略包，内置策略只解析一次
+func DefaultPolicy() *Policy {
+	defaultPolicyOnce.Do(func() {
+		policy, err := ParsePolicy(defaultPolicyYAML, false)
+		if err != nil {
+			panic(fmt.Sprintf("内置策略无效: %v", err))
+		}
+		defaultPolicy = policy
+	})
+
+	// 返回副本，避免调用方修改共享的默认策略
+	policy := *defaultPolicy
+	policy.Rules = append([]RuleDef(nil), defaultPolicy.Rules...)
+	return &policy
+}
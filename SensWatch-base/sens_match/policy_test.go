@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	const yamlPolicy = `
+version: "2"
+masking:
+  mode: fingerprint
+rules:
+  - id: staff_no
+    name: 工号
+    number: 1
+    pattern: 'GH-(\d{6})'
+    group: 1
+    severity: low
+    min_hits: 2
+  - id: phone
+    number: 2
+    pattern: '1[3-9]\d{9}'
+    enabled: false
+`
+	policy, err := ParsePolicy([]byte(yamlPolicy), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSensMatchWithPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := s.Registry().Rules()
+	if len(rules) != 1 || rules[0].ID() != "staff_no" {
+		t.Fatalf("启用的规则为 %v，期望只有 staff_no", rules)
+	}
+	rule := rules[0]
+	if rule.Severity() != SeverityLow || rule.MinHits() != 2 || rule.Mask() != MaskPartial || rule.Name() != "工号" {
+		t.Errorf("规则属性为 %s %d %s %s", rule.Severity(), rule.MinHits(), rule.Mask(), rule.Name())
+	}
+	if m := rule.Find("工号 GH-123456。"); len(m) != 1 || m[0].Value != "123456" || m[0].Offset != 10 {
+		t.Errorf("捕获组的命中为 %+v", m)
+	}
+	if policy.Masking.Mode != StoreFingerprint {
+		t.Errorf("打码模式为 %s", policy.Masking.Mode)
+	}
+
+	jsonPolicy := `{"version":"2","rules":[{"id":"staff_no","number":1,"pattern":"GH-\\d{6}"}]}`
+	if _, err := ParsePolicy([]byte(jsonPolicy), true); err != nil {
+		t.Errorf("解析 JSON 策略失败: %v", err)
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"没有规则", `rules: []`},
+		{"缺少 id", `rules: [{number: 1, pattern: a}]`},
+		{"编号无效", `rules: [{id: a, number: 0, pattern: a}]`},
+		{"编号重复", `rules: [{id: a, number: 1, pattern: a}, {id: b, number: 1, pattern: b}]`},
+		{"正则无效", `rules: [{id: a, number: 1, pattern: "("}]`},
+		{"捕获组不存在", `rules: [{id: a, number: 1, pattern: a, group: 1}]`},
+		{"未知的校验器", `rules: [{id: a, number: 1, pattern: a, validator: nope}]`},
+		{"未知的检测器", `rules: [{id: a, number: 1, detector: nope}]`},
+		{"同时设置 pattern 和 detector", `rules: [{id: a, number: 1, pattern: a, detector: address_name}]`},
+		{"敏感等级无效", `rules: [{id: a, number: 1, pattern: a, severity: critical}]`},
+		{"打码方式无效", `rules: [{id: a, number: 1, pattern: a, mask: md5}]`},
+	}
+	for _, tt := range tests {
+		policy, err := ParsePolicy([]byte(tt.rules), false)
+		if err == nil {
+			_, err = NewSensMatchWithPolicy(policy)
+		}
+		if err == nil {
+			t.Errorf("%s: 应当报错", tt.name)
+		}
+	}
+}
+
+func TestLoadPolicyOrDefault(t *testing.T) {
+	dir := t.TempDir()
+	policy, err := LoadPolicyOrDefault(filepath.Join(dir, "sens_policy.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.RuleSetVersion() != DefaultPolicy().RuleSetVersion() {
+		t.Errorf("策略文件不存在时应当使用默认策略")
+	}
+
+	path := writeFile(t, dir, "sens_policy.json", `{"version":"1","rules":[{"id":"a","number":1,"pattern":"a"}]}`)
+	custom, err := LoadPolicyOrDefault(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 版本号相同但规则不同时，规则集版本也不同
+	edited := *custom
+	edited.Rules = []RuleDef{{ID: "a", Number: 1, Pattern: "b"}}
+	if custom.RuleSetVersion() == edited.RuleSetVersion() || !strings.HasPrefix(custom.RuleSetVersion(), "1-") {
+		t.Errorf("规则集版本 %s 和 %s 应当不同", custom.RuleSetVersion(), edited.RuleSetVersion())
+	}
+
+	// 修改返回的默认策略不影响之后的调用
+	d := DefaultPolicy()
+	d.Rules[0].Pattern = "x"
+	if DefaultPolicy().Rules[0].Pattern == "x" {
+		t.Errorf("DefaultPolicy 返回了共享的规则")
+	}
+}
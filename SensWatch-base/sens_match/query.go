@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// QueryHit 表示查询值在某个文件中的一次出现
+type QueryHit struct {
+	FilePath string    `json:"file_path"`
+	RuleID   string    `json:"rule_id"`
+	Value    string    `json:"value"` // 数据库中保存的值，通常已打码
+	Offset   int64     `json:"offset"`
+	Line     int       `json:"line,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Context  string    `json:"context,omitempty"`
+}
+
+// queryFingerprints 计算查询值可能对应的指纹：原值、去掉分隔符的值，以及覆盖整个查询值的规则命中
+// 经校验器规范化后的值，使 "138 1234 5678" 与文件中的 "13812345678" 得到相同的指纹。
+// 只覆盖查询值一部分的命中（如身份证号中的出生日期被识别为电话号码）不参与查询
+func (p *FileProcessor) queryFingerprints(value string) []string {
+	value = strings.TrimSpace(value)
+	compact := strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(value)
+
+	seen := make(map[string]bool)
+	var fingerprints []string
+	add := func(v string) {
+		if v == "" {
+			return
+		}
+		fp := p.masker.Fingerprint(v)
+		if !seen[fp] {
+			seen[fp] = true
+			fingerprints = append(fingerprints, fp)
+		}
+	}
+
+	add(value)
+	add(compact)
+	for _, rule := range p.sensMatch.Registry().Rules() {
+		for _, m := range rule.Find(compact) {
+			if m.Offset == 0 && queryKey(m.Value) == queryKey(compact) {
+				add(m.Value)
+			}
+		}
+	}
+	return fingerprints
+}
+
+// queryKey 只保留字母和数字并转为大写，用于判断规范化后的命中值是否覆盖了整个查询值
+func queryKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
+
+// QueryValue 通过指纹在 sqlitePath 中查找包含 value 的所有文件，
+// 数据库中只保存了打码值时同样可以查到
+func (p *FileProcessor) QueryValue(sqlitePath, value string) ([]QueryHit, error) {
+	fingerprints := p.queryFingerprints(value)
+	if len(fingerprints) == 0 {
+		return nil, nil
+	}
+
+	db, err := openDatabase(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(fingerprints)), ",")
+	args := make([]interface{}, len(fingerprints))
+	for i, fp := range fingerprints {
+		args[i] = fp
+	}
+	rows, err := db.Query(`
+	SELECT d.file_path, m.rule_id, m.value, m.byte_offset, m.line, m.page, m.sheet, m.cell, m.slide, m.member, m.message_id, m.attachment, m.part, m.context
+	FROM matches m JOIN detection_results d ON d.id = m.file_id
+	WHERE m.fingerprint IN (`+placeholders+`)
+	ORDER BY d.file_path, m.member, m.byte_offset
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %v", err)
+	}
+	defer rows.Close()
+
+	var hits []QueryHit
+	for rows.Next() {
+		var hit QueryHit
+		var value, sheet, cell, member, messageID, attachment, part, context sql.NullString
+		var line, page, slide sql.NullInt64
+		if err := rows.Scan(&hit.FilePath, &hit.RuleID, &value, &hit.Offset, &line, &page, &sheet, &cell, &slide, &member, &messageID, &attachment, &part, &context); err != nil {
+			return nil, fmt.Errorf("查询数据库失败: %v", err)
+		}
+		hit.Value = value.String
+		hit.Line = int(line.Int64)
+		hit.Context = context.String
+		if page.Valid || sheet.Valid || cell.Valid || slide.Valid || member.Valid || messageID.Valid || attachment.Valid || part.Valid {
+			hit.Location = &Location{
+				Page:       int(page.Int64),
+				Sheet:      sheet.String,
+				Cell:       cell.String,
+				Slide:      int(slide.Int64),
+				Member:     member.String,
+				MessageID:  messageID.String,
+				Attachment: attachment.String,
+				Part:       part.String,
+			}
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %v", err)
+	}
+	return hits, nil
+}
+
+// printQueryHits 按文件分组输出查询结果
+func printQueryHits(value string, hits []QueryHit) {
+	if len(hits) == 0 {
+		fmt.Printf("没有文件包含: %s\n", value)
+		return
+	}
+
+	files := 0
+	for i, hit := range hits {
+		if i == 0 || hit.FilePath != hits[i-1].FilePath {
+			files++
+			fmt.Printf("%s\n", hit.FilePath)
+		}
+		where := fmt.Sprintf("第%d行 偏移%d", hit.Line, hit.Offset)
+		if hit.Location != nil {
+			where = hit.Location.String() + " " + where
+		}
+		fmt.Printf("    [%s] %s  %s", hit.RuleID, hit.Value, where)
+		if hit.Context != "" {
+			fmt.Printf("  %s", hit.Context)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("共 %d 个文件、%d 处包含该值\n", files, len(hits))
+}
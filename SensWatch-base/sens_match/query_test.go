@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestQueryValue(t *testing.T) {
+	dir := t.TempDir()
+	files := []FileInfo{
+		{Path: writeFile(t, dir, "id.txt", "身份证号110101199003077777")},
+		{Path: writeFile(t, dir, "phone.txt", "手机19900307777")},
+		{Path: writeFile(t, dir, "telephone.txt", "座机 99003077")},
+		{Path: writeFile(t, dir, "gender.txt", "性别：男，民族：汉族")},
+	}
+	p := NewFileProcessor()
+	sqlitePath := filepath.Join(dir, "output.db")
+	results, scope := p.ProcessFileList(files, []string{dir})
+	if err := ExportToSQLite(sqlitePath, results, scope); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		// 身份证号中的出生日期等数字会被识别为手机号和固定电话，但不应查到只包含这些数字的文件
+		{"身份证号", "110101199003077777", []string{"id.txt"}},
+		{"带分隔符的手机号", "199 0030 7777", []string{"id.txt", "phone.txt"}},
+		{"查询值中的一部分命中其它规则", "张三 男", nil},
+	}
+	for _, tt := range tests {
+		hits, err := p.QueryValue(sqlitePath, tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		seen := make(map[string]bool)
+		for _, hit := range hits {
+			if name := filepath.Base(hit.FilePath); !seen[name] {
+				seen[name] = true
+				got = append(got, name)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 查询 %q 得到 %q，期望 %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
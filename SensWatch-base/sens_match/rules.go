@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Severity 表示规则的敏感等级
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Match 表示一次规则命中
+type Match struct {
+	Value       string    `json:"value"`
+	Offset      int64     `json:"offset"`                // 命中值在提取文本中的字节偏移
+	Line        int       `json:"line,omitempty"`        // 命中值在提取文本中的行号，从 1 开始
+	Location    *Location `json:"location,omitempty"`    // 读取器提供的文档位置
+	Context     string    `json:"context,omitempty"`     // 命中值前后的上下文，命中值本身已打码
+	Fingerprint string    `json:"fingerprint,omitempty"` // 命中原值的 HMAC 指纹，用于去重和检索
+}
+
+// Rule 表示一条敏感信息检测规则
+type Rule interface {
+	ID() string         // 规则标识，如 "phone"
+	Name() string       // 规则的中文名称
+	Number() int        // 规则编号，写入 rule_numbers
+	Severity() Severity // 敏感等级
+	MinHits() int       // 单个文件中至少命中多少次才上报
+	Mask() MaskStrategy // 命中值落盘前的打码方式
+	Detector() string   // 使用的内置检测器，如 "type_mismatch"，正则规则为空字符串
+	Find(text string) []Match
+}
+
+// renumberedRule 覆盖已注册规则的编号
+type renumberedRule struct {
+	Rule
+	number int
+}
+
+func (r *renumberedRule) Number() int { return r.number }
+
+// ruleEntry 表示注册表中的一条记录
+type ruleEntry struct {
+	rule    Rule
+	enabled bool
+}
+
+// RuleRegistry 管理所有检测规则，支持新增、禁用和重新编号。
+// 注册表可以被多个 goroutine 并发读取
+type RuleRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]*ruleEntry
+	enabled []Rule // 已排序的启用规则，每次修改后重建
+}
+
+// NewRuleRegistry 创建空的规则注册表
+func NewRuleRegistry() *RuleRegistry {
+	return &RuleRegistry{
+		entries: make(map[string]*ruleEntry),
+	}
+}
+
+// Register 注册一条启用的规则，规则标识和编号都不能重复
+func (r *RuleRegistry) Register(rule Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[rule.ID()]; ok {
+		return fmt.Errorf("规则已存在: %s", rule.ID())
+	}
+	if other := r.findByNumber(rule.Number()); other != nil {
+		return fmt.Errorf("规则编号 %d 已被 %s 使用", rule.Number(), other.ID())
+	}
+	r.entries[rule.ID()] = &ruleEntry{rule: rule, enabled: true}
+	r.rebuild()
+	return nil
+}
+
+// Unregister 移除规则
+func (r *RuleRegistry) Unregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, id)
+	r.rebuild()
+}
+
+// SetEnabled 启用或禁用规则
+func (r *RuleRegistry) SetEnabled(id string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return fmt.Errorf("规则不存在: %s", id)
+	}
+	entry.enabled = enabled
+	r.rebuild()
+	return nil
+}
+
+// Renumber 修改规则编号
+func (r *RuleRegistry) Renumber(id string, number int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return fmt.Errorf("规则不存在: %s", id)
+	}
+	if other := r.findByNumber(number); other != nil && other.ID() != id {
+		return fmt.Errorf("规则编号 %d 已被 %s 使用", number, other.ID())
+	}
+	if rr, ok := entry.rule.(*renumberedRule); ok {
+		entry.rule = rr.Rule
+	}
+	if entry.rule.Number() != number {
+		entry.rule = &renumberedRule{Rule: entry.rule, number: number}
+	}
+	r.rebuild()
+	return nil
+}
+
+// rebuild 重建已排序的启用规则列表，调用方需持有写锁
+func (r *RuleRegistry) rebuild() {
+	rules := make([]Rule, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if entry.enabled {
+			rules = append(rules, entry.rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Number() < rules[j].Number()
+	})
+	r.enabled = rules
+}
+
+// findByNumber 查找使用指定编号的规则，调用方需持有锁
+func (r *RuleRegistry) findByNumber(number int) Rule {
+	for _, entry := range r.entries {
+		if entry.rule.Number() == number {
+			return entry.rule
+		}
+	}
+	return nil
+}
+
+// Lookup 根据规则标识查找规则
+func (r *RuleRegistry) Lookup(id string) (Rule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.rule, true
+}
+
+// Enabled 返回规则是否启用
+func (r *RuleRegistry) Enabled(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[id]
+	return ok && entry.enabled
+}
+
+// Rules 返回所有启用的规则，按编号排序。返回的切片只读，不要修改
+func (r *RuleRegistry) Rules() []Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.enabled
+}
+
+// AllRules 返回所有已注册的规则（包括禁用的），按编号排序
+func (r *RuleRegistry) AllRules() []Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(r.entries))
+	for _, entry := range r.entries {
+		rules = append(rules, entry.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Number() < rules[j].Number()
+	})
+	return rules
+}
+
+// RuleNumber 根据规则标识获取规则编号
+func (r *RuleRegistry) RuleNumber(id string) (int, bool) {
+	rule, ok := r.Lookup(id)
+	if !ok {
+		return 0, false
+	}
+	return rule.Number(), true
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestRuleRegistry(t *testing.T) {
+	s := NewSensMatch()
+	registry := s.Registry()
+
+	var ids []string
+	for _, rule := range registry.Rules() {
+		ids = append(ids, rule.ID())
+	}
+	if ids[0] != "phone" || ids[len(ids)-1] != "type_mismatch" || len(ids) != 20 {
+		t.Fatalf("默认规则为 %v，期望按编号排列的 20 条规则", ids)
+	}
+
+	phone, _ := registry.Lookup("phone")
+	tests := []struct {
+		name    string
+		op      func() error
+		wantErr bool
+	}{
+		{"重复的规则标识", func() error { return registry.Register(phone) }, true},
+		{"禁用不存在的规则", func() error { return registry.SetEnabled("nope", false) }, true},
+		{"编号已被使用", func() error { return registry.Renumber("phone", 2) }, true},
+		{"修改编号", func() error { return registry.Renumber("phone", 99) }, false},
+		{"禁用规则", func() error { return registry.SetEnabled("email", false) }, false},
+	}
+	for _, tt := range tests {
+		if err := tt.op(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v，期望出错: %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if num, _ := registry.RuleNumber("phone"); num != 99 {
+		t.Errorf("修改后的编号为 %d，期望 99", num)
+	}
+	if rules := registry.Rules(); rules[len(rules)-1].ID() != "phone" {
+		t.Errorf("修改编号后启用的规则没有重新排序")
+	}
+	if registry.Enabled("email") || len(registry.Rules()) != 19 || len(registry.AllRules()) != 20 {
+		t.Errorf("禁用的规则仍然启用")
+	}
+	if len(NewSensMatch().Registry().Rules()) != 20 {
+		t.Errorf("修改一个实例的注册表影响了其它实例")
+	}
+}
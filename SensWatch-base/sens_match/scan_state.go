@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// scanEngineVersion 文本提取和匹配逻辑的版本，读取器或扫描器的行为变化时递增，
+// 使旧的缓存结果失效
+const scanEngineVersion = 8
+
+// fileState 表示一个文件上次扫描时的指纹和结果
+type fileState struct {
+	Size         int64
+	ModifiedTime float64
+	MD5          string
+	RuleVersion  string
+	Result       string // SensitiveInfo 的 JSON
+}
+
+// ScanState 记录每个文件上次扫描时的指纹，用于跳过未变化的文件。
+// 状态启动时全部读入内存，可以被多个扫描 goroutine 并发访问，调用 Flush 写回数据库
+type ScanState struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	states  map[string]fileState
+	dirty   map[string]bool
+	removed map[string]bool
+}
+
+// OpenScanState 打开 sqlitePath 中的扫描状态表
+func OpenScanState(sqlitePath string) (*ScanState, error) {
+	db, err := openDatabase(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT file_path, size, modified_time, md5, rule_version, result FROM scan_state")
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("读取扫描状态失败: %v", err)
+	}
+	defer rows.Close()
+
+	s := &ScanState{
+		db:      db,
+		states:  make(map[string]fileState),
+		dirty:   make(map[string]bool),
+		removed: make(map[string]bool),
+	}
+	for rows.Next() {
+		var path string
+		var st fileState
+		var result sql.NullString
+		if err := rows.Scan(&path, &st.Size, &st.ModifiedTime, &st.MD5, &st.RuleVersion, &result); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("读取扫描状态失败: %v", err)
+		}
+		st.Result = result.String
+		s.states[path] = st
+	}
+	if err := rows.Err(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("读取扫描状态失败: %v", err)
+	}
+	return s, nil
+}
+
+// get 返回文件上次扫描的状态
+func (s *ScanState) get(path string) (fileState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[path]
+	return st, ok
+}
+
+// put 更新文件的扫描状态
+func (s *ScanState) put(path string, st fileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[path] = st
+	s.dirty[path] = true
+	delete(s.removed, path)
+}
+
+// Remove 删除文件的扫描状态，用于文件被删除或移走时。path 是目录时删除其下所有文件的状态
+func (s *ScanState) Remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := path + string(filepath.Separator)
+	for p := range s.states {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(s.states, p)
+			delete(s.dirty, p)
+			s.removed[p] = true
+		}
+	}
+}
+
+// Prune 删除 scope 中已被删除或不再需要扫描的文件的扫描状态，scope 为 nil 时删除所有状态
+func (s *ScanState) Prune(scope *ScanScope) {
+	s.mu.Lock()
+	var stale []string
+	for path := range s.states {
+		if scope == nil || scope.Gone(path) {
+			stale = append(stale, path)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, path := range stale {
+		s.Remove(path)
+	}
+}
+
+// Flush 把修改过的状态写回数据库
+func (s *ScanState) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	for path := range s.removed {
+		if _, err := tx.Exec("DELETE FROM scan_state WHERE file_path = ?", path); err != nil {
+			return fmt.Errorf("删除扫描状态失败: %v", err)
+		}
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT OR REPLACE INTO scan_state
+	(file_path, size, modified_time, md5, rule_version, result, scanned_at)
+	VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		return fmt.Errorf("准备扫描状态写入语句失败: %v", err)
+	}
+	defer stmt.Close()
+
+	for path := range s.dirty {
+		st := s.states[path]
+		if _, err := stmt.Exec(path, st.Size, st.ModifiedTime, st.MD5, st.RuleVersion, st.Result); err != nil {
+			return fmt.Errorf("写入扫描状态失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	s.dirty = make(map[string]bool)
+	s.removed = make(map[string]bool)
+	return nil
+}
+
+// Close 关闭数据库连接
+func (s *ScanState) Close() error {
+	return s.db.Close()
+}
+
+// SetScanState 启用增量扫描
+func (p *FileProcessor) SetScanState(state *ScanState) {
+	p.state = state
+}
+
+// ruleVersion 返回当前规则集版本。规则、打码设置、指纹密钥或扫描引擎变化时，
+// 已缓存的结果都不能再复用
+func (p *FileProcessor) ruleVersion() string {
+	return fmt.Sprintf("%s-%s-e%d", p.policyVersion, p.masker.Fingerprint("")[:8], scanEngineVersion)
+}
+
+// processIncremental 文件指纹和规则版本都没有变化时复用上次的结果，否则重新扫描
+func (p *FileProcessor) processIncremental(filePath string) (*SensitiveInfo, error) {
+	if p.state == nil {
+		return p.ProcessFile(filePath)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件绝对路径失败: %v", err)
+	}
+	stat, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	cur := fileState{
+		Size:         stat.Size(),
+		ModifiedTime: float64(stat.ModTime().UnixNano()) / 1e9,
+		RuleVersion:  p.ruleVersion(),
+	}
+
+	if prev, ok := p.state.get(absPath); ok && prev.RuleVersion == cur.RuleVersion {
+		// 大小和修改时间都没变，不需要重新计算MD5
+		if prev.Size == cur.Size && prev.ModifiedTime == cur.ModifiedTime {
+			return prev.result()
+		}
+		// 修改时间变了但内容没变，只更新指纹
+		md5Value, err := calculateMD5(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("计算MD5失败: %v", err)
+		}
+		if md5Value == prev.MD5 {
+			cur.MD5 = prev.MD5
+			cur.Result = prev.Result
+			p.state.put(absPath, cur)
+			return prev.result()
+		}
+	}
+
+	info, err := p.ProcessFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("序列化扫描结果失败: %v", err)
+	}
+	cur.MD5 = info.MD5
+	cur.Result = string(result)
+	p.state.put(absPath, cur)
+	return info, nil
+}
+
+// result 解析缓存的扫描结果，返回的结果标记为复用缓存
+func (st fileState) result() (*SensitiveInfo, error) {
+	var info SensitiveInfo
+	if err := json.Unmarshal([]byte(st.Result), &info); err != nil {
+		return nil, fmt.Errorf("解析缓存的扫描结果失败: %v", err)
+	}
+	info.cached = true
+	return &info, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultBufferSize = 4096 // 每次读取4KB
+	defaultOverlap    = 256  // 相邻窗口重叠的字节数，应不小于最长的单个命中
+	contextRunes      = 20   // 上下文片段在命中值两侧各保留的字符数
+)
+
+// StreamScanner 以带重叠窗口的方式流式扫描文本。
+//
+// 每个窗口只接受起始偏移落在 [acceptFrom, acceptTo) 内的命中，acceptTo 距窗口
+// 末尾留出 overlap 字节；下一个窗口从 acceptTo 之前 overlap 字节处开始，
+// 这样跨越缓冲区边界的命中会在下一个窗口中完整出现，而重叠区内已接受的命中
+// 不会被重复上报。窗口边界总是落在完整的 UTF-8 字符上。
+type StreamScanner struct {
+	sensMatch  *SensMatch
+	masker     *Masker // 决定上下文中命中值的打码方式，为 nil 时都按 partial 打码
+	bufferSize int
+	overlap    int
+}
+
+// NewStreamScanner 创建流式扫描器，bufferSize 为每次读取的字节数，overlap 为窗口重叠字节数
+func NewStreamScanner(sensMatch *SensMatch, bufferSize, overlap int) *StreamScanner {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	// 重叠区过大时每个窗口都无法推进
+	if overlap > bufferSize/2 {
+		overlap = bufferSize / 2
+	}
+	return &StreamScanner{
+		sensMatch:  sensMatch,
+		bufferSize: bufferSize,
+		overlap:    overlap,
+	}
+}
+
+// SetMasker 设置打码器，上下文中出现的命中值按其所属规则的打码方式隐藏
+func (sc *StreamScanner) SetMasker(masker *Masker) {
+	sc.masker = masker
+}
+
+// contextMask 返回规则的命中值出现在上下文中时的打码方式
+func (sc *StreamScanner) contextMask(rule Rule) MaskStrategy {
+	if sc.masker == nil {
+		return MaskPartial
+	}
+	return sc.masker.strategy(rule)
+}
+
+// Scan 读取全部内容并返回每条规则的命中，命中偏移相对于整个文本。
+// 如果 r 实现了 Locator，命中会带上文档位置
+func (sc *StreamScanner) Scan(r io.Reader) (map[string][]Match, error) {
+	results := make(map[string][]Match)
+	readBuf := make([]byte, sc.bufferSize)
+	locator, _ := r.(Locator)
+
+	var window []byte    // 当前窗口内容
+	var base int64       // window[0] 在整个文本中的偏移
+	var acceptFrom int64 // 起始偏移小于它的命中已由前面的窗口上报
+	var lineAtBase int   // base 之前的换行数
+
+	for eof := false; !eof; {
+		n, err := io.ReadFull(r, readBuf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		window = append(window, readBuf[:n]...)
+
+		// 末尾不完整的 UTF-8 字符留到下一轮
+		end := len(window)
+		if !eof {
+			end = lastRuneBoundary(window)
+		}
+
+		acceptTo := base + int64(end)
+		if !eof {
+			acceptTo -= int64(sc.overlap)
+		}
+		if acceptTo <= acceptFrom && !eof {
+			// 窗口还不够大，继续读取
+			continue
+		}
+
+		text := string(window[:end])
+		found := make(map[string][]Match)
+		var spans []span
+		for _, rule := range sc.sensMatch.Registry().Rules() {
+			matches := rule.Find(text)
+			mask := sc.contextMask(rule)
+			for _, m := range matches {
+				spans = append(spans, span{int(m.Offset), int(m.Offset) + len(m.Value), mask})
+			}
+			found[rule.ID()] = matches
+		}
+		spans = mergeSpans(spans)
+
+		newlines := newlineOffsets(text)
+		for _, rule := range sc.sensMatch.Registry().Rules() {
+			for _, m := range found[rule.ID()] {
+				rel := int(m.Offset)
+				m.Offset += base
+				if m.Offset < acceptFrom || (!eof && m.Offset >= acceptTo) {
+					continue
+				}
+				m.Line = lineAtBase + sort.SearchInts(newlines, rel) + 1
+				m.Context = contextSnippet(text, rel, rel+len(m.Value), spans)
+				if locator != nil {
+					m.Location = locator.Locate(m.Offset)
+				}
+				results[rule.ID()] = append(results[rule.ID()], m)
+			}
+		}
+		acceptFrom = acceptTo
+
+		// 保留 acceptTo 之前 overlap 字节开始的内容作为下一个窗口的开头
+		keep := int(acceptTo-base) - sc.overlap
+		if keep < 0 {
+			keep = 0
+		}
+		for i := 0; i < utf8.UTFMax && keep < len(window) && !utf8.RuneStart(window[keep]); i++ {
+			keep++
+		}
+		lineAtBase += bytes.Count(window[:keep], []byte{'\n'})
+		window = window[:copy(window, window[keep:])]
+		base += int64(keep)
+	}
+
+	return results, nil
+}
+
+// lastRuneBoundary 返回 b 中最后一个完整 UTF-8 字符之后的位置
+func lastRuneBoundary(b []byte) int {
+	n := len(b)
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:n]) {
+				return n
+			}
+			return i
+		}
+	}
+	return n
+}
+
+// newlineOffsets 返回 text 中所有换行符的偏移
+func newlineOffsets(text string) []int {
+	var offsets []int
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// span 表示文本中的一段命中区间 [start, end)，mask 为其在上下文中的打码方式
+type span struct {
+	start, end int
+	mask       MaskStrategy
+}
+
+// maskLevel 打码方式的严格程度，合并重叠的区间时使用更严格的方式
+func maskLevel(mask MaskStrategy) int {
+	switch mask {
+	case "":
+		return 0
+	case MaskPartial:
+		return 1
+	}
+	return 2
+}
+
+// mergeSpans 排序并合并重叠的区间
+func mergeSpans(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			if maskLevel(s.mask) > maskLevel(merged[n-1].mask) {
+				merged[n-1].mask = s.mask
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// contextSnippet 截取 [start, end) 两侧各 contextRunes 个字符作为上下文。
+// 上下文中落在 masked 区间内的命中值（包括其它规则的命中）按各自规则的打码方式隐藏：
+// partial 保留首尾字符，hmac 和 redact 替换为占位符
+func contextSnippet(text string, start, end int, masked []span) string {
+	if end > len(text) {
+		end = len(text)
+	}
+	from := start
+	for i := 0; i < contextRunes && from > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := end
+	for i := 0; i < contextRunes && to < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+	var sb strings.Builder
+	pos := from
+	for _, s := range masked {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		// 被截断的命中整体打码，避免截断后保留的首尾字符泄露更多内容
+		clipped := s.start < from || s.end > to
+		s.start, s.end = max(s.start, from, pos), min(s.end, to)
+		if s.start > pos {
+			sb.WriteString(text[pos:s.start])
+		}
+		switch {
+		case s.mask == "":
+			sb.WriteString(text[s.start:s.end])
+		case s.mask != MaskPartial:
+			sb.WriteString(redactedValue)
+		case clipped:
+			sb.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[s.start:s.end])))
+		default:
+			sb.WriteString(maskPartial(text[s.start:s.end]))
+		}
+		pos = s.end
+	}
+	if pos < to {
+		sb.WriteString(text[pos:to])
+	}
+	snippet := sb.String()
+
+	// 合并换行和连续空白，便于在界面中单行展示
+	return strings.Join(strings.Fields(snippet), " ")
+}
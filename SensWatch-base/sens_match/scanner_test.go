@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamScannerChunkBoundary(t *testing.T) {
+	const phone = "13812345678"
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"跨越 4KB 边界", strings.Repeat("x", 4090)},
+		{"紧贴 4KB 边界", strings.Repeat("x", 4096)},
+		{"落在重叠区内", strings.Repeat("x", 4096-100)},
+		{"边界切开多字节字符", "第一行\nx" + strings.Repeat("中", 1362)},
+		{"多次读取之后", strings.Repeat("x", 3*4096-5)},
+	}
+	s := NewSensMatch()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := tt.prefix + phone + " 结尾\n"
+			results, err := NewStreamScanner(s, 4096, 256).Scan(strings.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := results["phone"]
+			if len(got) != 1 {
+				t.Fatalf("命中 %d 次，期望 1 次: %+v", len(got), got)
+			}
+			wantLine := strings.Count(tt.prefix, "\n") + 1
+			if got[0].Value != phone || got[0].Offset != int64(len(tt.prefix)) || got[0].Line != wantLine {
+				t.Errorf("命中为 %q 偏移 %d 第 %d 行，期望偏移 %d 第 %d 行",
+					got[0].Value, got[0].Offset, got[0].Line, len(tt.prefix), wantLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// scratchRoot 解压压缩包成员和邮件附件时使用的私有临时目录，只有当前用户可以访问。
+// 每个进程使用其中以进程号命名的子目录，进程崩溃后留下的子目录在下次启动时删除，
+// 避免解压出的敏感内容长期留在公共的临时目录中
+var scratchRoot = filepath.Join(os.TempDir(), scratchRootName())
+
+// scratch 当前进程的临时目录，第一次使用时创建
+var scratch struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// scratchRootName 返回私有临时目录的名称，类 Unix 系统上按用户区分
+func scratchRootName() string {
+	if uid := os.Getuid(); uid >= 0 {
+		return "sens_match-" + strconv.Itoa(uid)
+	}
+	return "sens_match"
+}
+
+// prepareScratchRoot 创建私有临时目录，并确认它不是其他用户预先创建的目录或符号链接
+func prepareScratchRoot(root string) error {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	info, err := os.Lstat(root)
+	if err != nil {
+		return fmt.Errorf("获取临时目录信息失败: %v", err)
+	}
+	if !info.IsDir() || !privateDir(info) {
+		return fmt.Errorf("临时目录 %s 不属于当前用户或其他用户可以访问", root)
+	}
+	return nil
+}
+
+// scratchDir 返回当前进程的临时目录，不存在时创建
+func scratchDir() (string, error) {
+	scratch.mu.Lock()
+	defer scratch.mu.Unlock()
+	if scratch.dir != "" {
+		return scratch.dir, nil
+	}
+	if err := prepareScratchRoot(scratchRoot); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(scratchRoot, strconv.Itoa(os.Getpid()))
+	// 同一进程号的旧目录属于已经退出的进程
+	os.RemoveAll(dir)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	scratch.dir = dir
+	return dir, nil
+}
+
+// createScratchFile 在当前进程的临时目录中创建临时文件，pattern 与 os.CreateTemp 相同
+func createScratchFile(pattern string) (*os.File, error) {
+	dir, err := scratchDir()
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, pattern)
+}
+
+// removeScratch 删除当前进程的临时目录，进程退出前调用
+func removeScratch() {
+	scratch.mu.Lock()
+	defer scratch.mu.Unlock()
+	if scratch.dir != "" {
+		os.RemoveAll(scratch.dir)
+		scratch.dir = ""
+	}
+}
+
+// removeStaleScratch 删除 root 中已经退出的进程留下的临时目录
+func removeStaleScratch(root string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			fmt.Printf("删除残留的临时目录失败: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestRemoveStaleScratch(t *testing.T) {
+	root := t.TempDir()
+	// 进程号超过 Linux 的 pid_max 上限，不可能在运行
+	stale := filepath.Join(root, "2000000000")
+	alive := filepath.Join(root, strconv.Itoa(os.Getppid()))
+	own := filepath.Join(root, strconv.Itoa(os.Getpid()))
+	other := filepath.Join(root, "not-a-pid")
+	for _, dir := range []string{stale, alive, own, other} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "archive_1"), []byte("13812345678"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removeStaleScratch(root)
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("已退出的进程留下的临时目录没有删除: %v", err)
+	}
+	for _, dir := range []string{alive, own, other} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s 不应删除: %v", filepath.Base(dir), err)
+		}
+	}
+}
+
+func TestCreateScratchFile(t *testing.T) {
+	f, err := createScratchFile("archive_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer removeScratch()
+
+	dir := filepath.Dir(f.Name())
+	if filepath.Dir(dir) != scratchRoot || filepath.Base(dir) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("临时文件 %s 不在本进程的私有临时目录中", f.Name())
+	}
+	info, err := os.Stat(scratchRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privateDir(info) {
+		t.Errorf("临时目录 %s 的权限为 %v，其他用户可以访问", scratchRoot, info.Mode())
+	}
+}
+
+func TestPrepareScratchRootRejectsSharedDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 下不检查临时目录的权限")
+	}
+	root := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareScratchRoot(root); err == nil {
+		t.Error("其他用户可以访问的临时目录应当被拒绝")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// processAlive 判断进程是否仍在运行，Windows 下进程不存在时 os.FindProcess 返回错误
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// privateDir Windows 的临时目录本身位于用户目录下，不再检查权限
+func privateDir(info os.FileInfo) bool {
+	return true
+}
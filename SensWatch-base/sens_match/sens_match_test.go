@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchmarkCorpus 生成约 size 字节的混合中英文文本，其中穿插各类敏感信息
+func benchmarkCorpus(size int) string {
+	var sb strings.Builder
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "第%d条记录：张三，男，汉族，手机13812345%03d，", i, i%1000)
+		fmt.Fprintf(&sb, "身份证号11010119900307%04d，邮箱user%d@example.com，", i%10000, i)
+		fmt.Fprintf(&sb, "登录IP 192.168.%d.%d，银行卡6222020200112233445。", i%256, (i*7)%256)
+		sb.WriteString("The quick brown fox jumps over the lazy dog. 这是一段普通的说明文字，不包含敏感信息。\n")
+	}
+	return sb.String()
+}
+
+// chunks 按 4KB 切分文本，与 ProcessFile 的读取方式一致
+func chunks(text string, size int) []string {
+	var out []string
+	for len(text) > size {
+		out = append(out, text[:size])
+		text = text[size:]
+	}
+	return append(out, text)
+}
+
+// BenchmarkRunAllChecks 使用预编译并共享的正则
+func BenchmarkRunAllChecks(b *testing.B) {
+	corpus := benchmarkCorpus(1 << 20)
+	parts := chunks(corpus, 4096)
+	s := NewSensMatch()
+
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, part := range parts {
+			s.RunAllChecks(part)
+		}
+	}
+}
+
+// BenchmarkRunAllChecksParallel 多个 goroutine 共享同一个 SensMatch
+func BenchmarkRunAllChecksParallel(b *testing.B) {
+	corpus := benchmarkCorpus(1 << 20)
+	parts := chunks(corpus, 4096)
+	s := NewSensMatch()
+
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, part := range parts {
+				s.RunAllChecks(part)
+			}
+		}
+	})
+}
+
+// BenchmarkRunAllChecksRecompile 模拟旧实现：每个分块都重新编译所有正则，
+// 规则、校验器和地址人名检测与 BenchmarkRunAllChecks 相同
+func BenchmarkRunAllChecksRecompile(b *testing.B) {
+	corpus := benchmarkCorpus(1 << 20)
+	parts := chunks(corpus, 4096)
+	policy := DefaultPolicy()
+
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, part := range parts {
+			// 清空正则缓存，使每个分块都重新编译
+			patternCache.Range(func(key, _ any) bool {
+				patternCache.Delete(key)
+				return true
+			})
+			s, err := NewSensMatchWithPolicy(policy)
+			if err != nil {
+				b.Fatal(err)
+			}
+			s.RunAllChecks(part)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// ContentType 根据文件头部的特征字节识别出的文件类型
+type ContentType string
+
+const (
+	TypeEmpty   ContentType = "empty"
+	TypeText    ContentType = "text"
+	TypeUTF16LE ContentType = "utf16le"
+	TypeUTF16BE ContentType = "utf16be"
+	TypeBinary  ContentType = "binary" // 无法识别的二进制内容
+	TypeDocx    ContentType = "docx"
+	TypeXlsx    ContentType = "xlsx"
+	TypePptx    ContentType = "pptx"
+	TypeZip     ContentType = "zip"
+	TypePDF     ContentType = "pdf"
+	TypeOLE     ContentType = "ole" // 无法进一步识别的 OLE 复合文档，如 .msg、.msi
+	TypeDoc     ContentType = "doc"
+	TypeXls     ContentType = "xls"
+	TypePpt     ContentType = "ppt"
+	TypeOdt     ContentType = "odt"
+	TypeOds     ContentType = "ods"
+	TypeOdp     ContentType = "odp"
+	TypePNG     ContentType = "png"
+	TypeJPEG    ContentType = "jpeg"
+	TypeGIF     ContentType = "gif"
+	TypeBMP     ContentType = "bmp"
+	TypeWebP    ContentType = "webp"
+	TypeICO     ContentType = "ico"
+	TypeMP3     ContentType = "mp3"
+	TypeMP4     ContentType = "mp4"
+	TypeAVI     ContentType = "avi"
+	TypeASF     ContentType = "asf" // .wmv、.wma
+	TypeRAR     ContentType = "rar"
+	Type7z      ContentType = "7z"
+	TypeGzip    ContentType = "gzip"
+	TypeTar     ContentType = "tar"
+	TypeExe     ContentType = "exe"
+	TypeELF     ContentType = "elf"
+	TypeMachO   ContentType = "macho"
+	TypeEml     ContentType = "eml"
+	TypeMbox    ContentType = "mbox"
+)
+
+// sniffLen 识别文件类型时读取的文件头长度，需要覆盖 tar 头部 257 字节处的 "ustar"
+const sniffLen = 1024
+
+// textExtensions 常见的纯文本文件扩展名
+var textExtensions = []string{
+	".txt", ".csv", ".tsv", ".log", ".md", ".json", ".xml", ".html", ".htm",
+	".ini", ".conf", ".cfg", ".yaml", ".yml", ".sql", ".properties", ".svg",
+	".eml", ".mbox",
+}
+
+// contentExtensions 每种内容类型常用的扩展名，用于判断扩展名与内容是否相符
+var contentExtensions = map[ContentType][]string{
+	TypeDocx:  {".docx", ".docm", ".dotx"},
+	TypeXlsx:  {".xlsx", ".xlsm", ".xltx"},
+	TypePptx:  {".pptx", ".pptm", ".potx"},
+	TypeZip:   {".zip", ".jar"},
+	TypePDF:   {".pdf"},
+	TypeDoc:   {".doc", ".dot"},
+	TypeXls:   {".xls", ".xlt"},
+	TypePpt:   {".ppt", ".pot", ".pps"},
+	TypeOdt:   {".odt", ".ott"},
+	TypeOds:   {".ods", ".ots"},
+	TypeOdp:   {".odp", ".otp"},
+	TypeOLE:   {".doc", ".dot", ".xls", ".xlt", ".ppt", ".pot", ".pps", ".msg", ".msi"},
+	TypePNG:   {".png"},
+	TypeJPEG:  {".jpg", ".jpeg"},
+	TypeGIF:   {".gif"},
+	TypeBMP:   {".bmp"},
+	TypeWebP:  {".webp"},
+	TypeICO:   {".ico"},
+	TypeMP3:   {".mp3"},
+	TypeMP4:   {".mp4", ".mov", ".m4a"},
+	TypeAVI:   {".avi"},
+	TypeASF:   {".wmv", ".wma"},
+	TypeRAR:   {".rar"},
+	Type7z:    {".7z"},
+	TypeGzip:  {".gz", ".tgz"},
+	TypeTar:   {".tar"},
+	TypeExe:   {".exe", ".dll", ".sys"},
+	TypeELF:   {".so", ".o"},
+	TypeMachO: {".dylib"},
+}
+
+// extensionTypes 扩展名 -> 与之相符的内容类型
+var extensionTypes = func() map[string][]ContentType {
+	m := make(map[string][]ContentType)
+	for t, exts := range contentExtensions {
+		for _, ext := range exts {
+			m[ext] = append(m[ext], t)
+		}
+	}
+	// 邮件也是文本，.txt 中保存的邮件和没有识别为邮件的 .eml 都不算不符
+	for _, ext := range textExtensions {
+		m[ext] = append(m[ext], TypeText, TypeUTF16LE, TypeUTF16BE, TypeEml, TypeMbox)
+	}
+	return m
+}()
+
+// Scannable 返回该类型是否有可用的读取器
+func (t ContentType) Scannable() bool {
+	switch t {
+	case TypeEmpty, TypeText, TypeUTF16LE, TypeUTF16BE, TypeDocx, TypeXlsx, TypePptx, TypePDF,
+		TypeDoc, TypeXls, TypePpt, TypeOdt, TypeOds, TypeOdp, TypeEml, TypeMbox:
+		return true
+	}
+	return false
+}
+
+// unsupportedTypeError 表示文件内容不是可以检测的类型
+type unsupportedTypeError struct {
+	path  string
+	ctype ContentType
+}
+
+func (e *unsupportedTypeError) Error() string {
+	return fmt.Sprintf("跳过不支持的文件类型: %s（%s）", e.path, e.ctype)
+}
+
+// sniffFile 读取文件头部识别文件类型，ZIP 和 OLE 复合文档再根据其中的条目区分 Office 文档
+func sniffFile(path string) (ContentType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("读取文件头失败: %v", err)
+	}
+
+	t := sniffBytes(head[:n])
+	switch t {
+	case TypeZip:
+		stat, err := f.Stat()
+		if err != nil {
+			return "", fmt.Errorf("获取文件信息失败: %v", err)
+		}
+		t = sniffZip(f, stat.Size())
+	case TypeOLE:
+		t = sniffOLE(f)
+	case TypeText:
+		t = sniffMail(head[:n])
+	}
+	return t, nil
+}
+
+// sniffBytes 根据文件头部的特征字节识别类型
+func sniffBytes(head []byte) ContentType {
+	has := func(offset int, magic string) bool {
+		return len(head) >= offset+len(magic) && string(head[offset:offset+len(magic)]) == magic
+	}
+
+	switch {
+	case len(head) == 0:
+		return TypeEmpty
+	case has(0, "%PDF-"):
+		return TypePDF
+	case has(0, "PK\x03\x04"), has(0, "PK\x05\x06"):
+		return TypeZip
+	case has(0, "\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"):
+		return TypeOLE
+	case has(0, "\x89PNG\r\n\x1a\n"):
+		return TypePNG
+	case has(0, "\xFF\xD8\xFF"):
+		return TypeJPEG
+	case has(0, "GIF87a"), has(0, "GIF89a"):
+		return TypeGIF
+	case has(0, "BM") && len(head) >= 26 && has(6, "\x00\x00\x00\x00"):
+		return TypeBMP
+	case has(0, "RIFF") && has(8, "WEBP"):
+		return TypeWebP
+	case has(0, "RIFF") && has(8, "AVI "):
+		return TypeAVI
+	case has(0, "\x00\x00\x01\x00"):
+		return TypeICO
+	case has(0, "ID3"), has(0, "\xFF\xFB"), has(0, "\xFF\xF3"), has(0, "\xFF\xF2"):
+		return TypeMP3
+	case has(4, "ftyp"):
+		return TypeMP4
+	case has(0, "\x30\x26\xB2\x75\x8E\x66\xCF\x11"):
+		return TypeASF
+	case has(0, "Rar!\x1a\x07"):
+		return TypeRAR
+	case has(0, "7z\xBC\xAF\x27\x1C"):
+		return Type7z
+	case has(0, "\x1F\x8B"):
+		return TypeGzip
+	case has(257, "ustar"):
+		return TypeTar
+	case has(0, "MZ") && bytes.IndexByte(head, 0) >= 0:
+		return TypeExe
+	case has(0, "\x7FELF"):
+		return TypeELF
+	case has(0, "\xFE\xED\xFA\xCE"), has(0, "\xFE\xED\xFA\xCF"), has(0, "\xCE\xFA\xED\xFE"), has(0, "\xCF\xFA\xED\xFE"):
+		return TypeMachO
+	case has(0, "\xFF\xFE"):
+		return TypeUTF16LE
+	case has(0, "\xFE\xFF"):
+		return TypeUTF16BE
+	case has(0, "\xEF\xBB\xBF"):
+		return TypeText
+	}
+	return sniffText(head)
+}
+
+// sniffText 区分文本和二进制内容。没有 BOM 的 UTF-16 文本中，ASCII 字符的高字节为 0，
+// 零字节集中在奇数或偶数位置；其它文本（包括 GBK 编码）不含零字节，控制字符也很少
+func sniffText(head []byte) ContentType {
+	var zeros [2]int
+	control := 0
+	for i, b := range head {
+		switch {
+		case b == 0:
+			zeros[i%2]++
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B:
+			control++
+		}
+	}
+
+	half := len(head) / 2
+	switch {
+	case zeros[0] == 0 && zeros[1] == 0:
+		if control*10 > len(head) {
+			return TypeBinary
+		}
+		return TypeText
+	case half > 0 && zeros[1]*5 >= half*2 && zeros[0]*20 < half:
+		return TypeUTF16LE
+	case half > 0 && zeros[0]*5 >= half*2 && zeros[1]*20 < half:
+		return TypeUTF16BE
+	}
+	return TypeBinary
+}
+
+// mailHeaderNames 识别邮件时使用的常见邮件头，按规范的大小写匹配，避免把 YAML 等配置文件当作邮件
+var mailHeaderNames = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Subject": true, "Date": true, "Message-Id": true, "Message-ID": true,
+	"Received": true, "Return-Path": true, "Mime-Version": true, "MIME-Version": true, "Content-Type": true,
+	"Reply-To": true, "Delivered-To": true, "X-Mailer": true,
+}
+
+// sniffMail 区分邮件和普通文本：文本开头是至少包含两个常见邮件头的邮件头块时为 eml，
+// 以 mbox 的分隔行 "From " 开头、下面紧接邮件头时为 mbox
+func sniffMail(head []byte) ContentType {
+	text := string(head)
+	t := TypeEml
+	if strings.HasPrefix(text, "From ") {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return TypeText
+		}
+		text, t = text[i+1:], TypeMbox
+	}
+
+	lines := strings.Split(text, "\n")
+	known := 0
+	// 最后一行可能被截断，不参与判断
+	for i, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if i == 0 {
+				return TypeText
+			}
+			continue
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return TypeText
+		}
+		if mailHeaderNames[name] {
+			known++
+		}
+	}
+	if known < 2 {
+		return TypeText
+	}
+	return t
+}
+
+// odfMimeTypes OpenDocument 的 mimetype 条目内容 -> 内容类型，模板与文档使用同一个读取器
+var odfMimeTypes = map[string]ContentType{
+	"application/vnd.oasis.opendocument.text":                  TypeOdt,
+	"application/vnd.oasis.opendocument.text-template":         TypeOdt,
+	"application/vnd.oasis.opendocument.spreadsheet":           TypeOds,
+	"application/vnd.oasis.opendocument.spreadsheet-template":  TypeOds,
+	"application/vnd.oasis.opendocument.presentation":          TypeOdp,
+	"application/vnd.oasis.opendocument.presentation-template": TypeOdp,
+}
+
+// sniffZip 根据 ZIP 中的条目区分 docx、xlsx、pptx、OpenDocument 文档和普通压缩包
+func sniffZip(r io.ReaderAt, size int64) ContentType {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return TypeZip
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return TypeDocx
+		case "xl/workbook.xml":
+			return TypeXlsx
+		case "ppt/presentation.xml":
+			return TypePptx
+		case "mimetype":
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			mime, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			if t, ok := odfMimeTypes[strings.TrimSpace(string(mime))]; ok {
+				return t
+			}
+		}
+	}
+	return TypeZip
+}
+
+// sniffOLE 根据复合文档根目录下的流区分 doc、xls 和 ppt
+func sniffOLE(r io.ReaderAt) ContentType {
+	doc, err := mscfb.New(r)
+	if err != nil {
+		return TypeOLE
+	}
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		if len(entry.Path) > 0 {
+			continue
+		}
+		switch entry.Name {
+		case "WordDocument":
+			return TypeDoc
+		case "Workbook", "Book":
+			return TypeXls
+		case "PowerPoint Document":
+			return TypePpt
+		}
+	}
+	return TypeOLE
+}
+
+// extensionMismatch 判断扩展名与实际内容是否不符。
+// 只上报扩展名声明为文本或文档（如 .txt 实际是无法识别的二进制内容）、或文档伪装成其它扩展名（如 .jpg 实际是 docx）的文件，
+// 图片、音视频、压缩包之间的不符（如 .m4a 实际是 mp4）通常无害，不上报。扩展名不常见或文件为空时不算不符
+func extensionMismatch(path string, t ContentType) bool {
+	if t == TypeEmpty {
+		return false
+	}
+	expected, ok := extensionTypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return false
+	}
+	declared := false
+	for _, e := range expected {
+		if e == t {
+			return false
+		}
+		declared = declared || e.Scannable()
+	}
+	return declared || t.Scannable()
+}
+
+// typeMismatchMatch 生成扩展名与内容不符的命中，命中值描述两者的差异
+func typeMismatchMatch(path string, t ContentType) Match {
+	return Match{Value: fmt.Sprintf("扩展名 %s，实际内容为 %s", strings.ToLower(filepath.Ext(path)), t)}
+}
+
+// typeMismatchRuleID 返回使用 type_mismatch 检测器的启用规则的标识，策略中没有启用该规则时返回空字符串
+func (p *FileProcessor) typeMismatchRuleID() string {
+	for _, rule := range p.sensMatch.Registry().Rules() {
+		if rule.Detector() == typeMismatchDetector {
+			return rule.ID()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip 在 dir 下创建 name，按顺序写入 entries 中的条目（条目名、内容交替）
+func writeZip(t *testing.T, dir, name string, entries ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for i := 0; i+1 < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entries[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeFile 在 dir 下创建内容为 data 的文件
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSniffFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+		want ContentType
+	}{
+		{"空文件", writeFile(t, dir, "empty.txt", ""), TypeEmpty},
+		{"纯文本", writeFile(t, dir, "a.txt", "联系人张三，电话13812345678\n"), TypeText},
+		{"UTF-8 BOM", writeFile(t, dir, "bom.txt", string(rune(0xFEFF))+"正文"), TypeText},
+		{"UTF-16LE BOM", writeFile(t, dir, "le.txt", "\xFF\xFEa\x00b\x00"), TypeUTF16LE},
+		{"无 BOM 的 UTF-16LE", writeFile(t, dir, "le2.txt", strings.Repeat("a\x00", 64)), TypeUTF16LE},
+		{"PDF", writeFile(t, dir, "a.pdf", "%PDF-1.7\n"), TypePDF},
+		{"PNG", writeFile(t, dir, "a.png", "\x89PNG\r\n\x1a\n\x00\x00"), TypePNG},
+		{"改名为 .txt 的 exe", writeFile(t, dir, "setup.txt", "MZ\x90\x00\x03\x00"), TypeExe},
+		{"二进制", writeFile(t, dir, "blob.txt", "\x01\x02\x03\x04\x05\x06\x07\x08\x00\x10"), TypeBinary},
+		{"docx", writeZip(t, dir, "a.docx", "[Content_Types].xml", "", "word/document.xml", "<w:document/>"), TypeDocx},
+		{"改名为 .jpg 的 xlsx", writeZip(t, dir, "a.jpg", "xl/workbook.xml", "<workbook/>"), TypeXlsx},
+		{"odt", writeZip(t, dir, "a.odt", "mimetype", "application/vnd.oasis.opendocument.text"), TypeOdt},
+		{"普通 zip", writeZip(t, dir, "a.zip", "hr/staff.txt", "x"), TypeZip},
+		{"gzip", writeFile(t, dir, "a.gz", "\x1F\x8B\x08\x00"), TypeGzip},
+		{"eml", writeFile(t, dir, "a.eml", "From: a@example.com\nTo: b@example.com\nSubject: hi\n\nbody\n"), TypeEml},
+		{"mbox", writeFile(t, dir, "a.mbox", "From a@example.com Mon Jan 1 00:00:00 2024\nFrom: a@example.com\nSubject: hi\n\nbody\n"), TypeMbox},
+		{"YAML 不是邮件", writeFile(t, dir, "a.yaml", "name: x\nversion: 1\n\n"), TypeText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sniffFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("sniffFile(%s) = %s，期望 %s", filepath.Base(tt.path), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtensionMismatch(t *testing.T) {
+	tests := []struct {
+		path string
+		t    ContentType
+		want bool
+	}{
+		{"a.docx", TypeDocx, false},
+		{"a.txt", TypeText, false},
+		{"a.txt", TypeEml, false},
+		{"a.doc", TypeOLE, false},
+		{"a.txt", TypeEmpty, false},
+		{"a.txt", TypeBinary, true},
+		{"a.txt", TypeExe, true},
+		{"a.csv", TypeZip, true},
+		{"a.pdf", TypePNG, true},
+		{"a.docx", TypeBinary, true},
+		{"a.jpg", TypeDocx, true},
+		{"a.zip", TypeXlsx, true},
+		{"a.m4a", TypeMP4, false},
+		{"a.jpg", TypePNG, false},
+		{"a.dll", TypeExe, false},
+		{"a.zip", TypeExe, false},
+		{"a.jpg", TypeBinary, false},
+		{"a.unknown", TypeDocx, false},
+		{"a.TXT", TypeBinary, true},
+	}
+	for _, tt := range tests {
+		if got := extensionMismatch(tt.path, tt.t); got != tt.want {
+			t.Errorf("extensionMismatch(%s, %s) = %v，期望 %v", tt.path, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestTypeMismatchRuleID(t *testing.T) {
+	p := NewFileProcessor()
+	if got := p.typeMismatchRuleID(); got != "type_mismatch" {
+		t.Fatalf("typeMismatchRuleID() = %q，期望 type_mismatch", got)
+	}
+	if err := p.sensMatch.Registry().SetEnabled("type_mismatch", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.typeMismatchRuleID(); got != "" {
+		t.Errorf("禁用后 typeMismatchRuleID() = %q，期望空字符串", got)
+	}
+}
@@ -21,6 +21,9 @@ type SensitiveInfo struct {
 	DetectTime          string              `json:"detect_time"`
 	MatchCounts         map[string]int      `json:"match_counts"`
 	Matches             map[string][]string `json:"matches"`
+	MatchDetails        map[string][]Match  `json:"match_details"` // 每个命中的偏移、行号、位置和上下文
 	TotalSensitiveCount int                 `json:"total_sensitive_count"`
 	RuleNumbers         string              `json:"rule_numbers"`
+
+	cached bool // 结果复用自上次扫描的缓存，文件没有重新扫描
 }
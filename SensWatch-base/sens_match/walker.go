@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymlinkPolicy 表示遍历目录时如何处理符号链接
+type SymlinkPolicy string
+
+const (
+	SymlinkSkip   SymlinkPolicy = "skip"   // 忽略符号链接（默认）
+	SymlinkFollow SymlinkPolicy = "follow" // 跟随符号链接，已经访问过的目录不会重复进入
+)
+
+// WalkOptions 目录遍历选项
+type WalkOptions struct {
+	Include       []string      // 只扫描匹配的文件，为空时扫描所有文件
+	Exclude       []string      // 跳过匹配的文件和目录
+	Symlinks      SymlinkPolicy // 符号链接处理方式
+	MaxDepth      int           // 最大遍历深度，根目录下的文件深度为 1，<= 0 表示不限制
+	IncludeHidden bool          // 是否扫描隐藏文件、隐藏目录和临时文件
+	OneFileSystem bool          // 不进入挂载在其它文件系统上的目录
+}
+
+// WalkFiles 遍历 roots 下的所有文件，代替 read_path.py 生成的文件索引。
+// 通配符相对于根目录匹配，"/" 作为分隔符，"**" 匹配任意层目录；
+// 不含 "/" 的通配符只匹配文件名或目录名，如 "*.docx"
+func WalkFiles(roots []string, opts WalkOptions) ([]FileInfo, error) {
+	switch opts.Symlinks {
+	case "":
+		opts.Symlinks = SymlinkSkip
+	case SymlinkSkip, SymlinkFollow:
+	default:
+		return nil, fmt.Errorf("不支持的符号链接处理方式: %s", opts.Symlinks)
+	}
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("通配符格式错误 %q: %v", pattern, err)
+		}
+	}
+
+	w := &walker{opts: opts, visited: make(map[string]bool), seen: make(map[string]bool)}
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("获取目录绝对路径失败: %v", err)
+		}
+		info, err := os.Stat(absRoot)
+		if err != nil {
+			return nil, fmt.Errorf("读取目录失败: %v", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("不是目录: %s", root)
+		}
+		w.rootDev, w.hasDev = deviceID(info)
+		w.walkDir(absRoot, "", 0)
+	}
+
+	sort.Slice(w.files, func(i, j int) bool {
+		return w.files[i].Path < w.files[j].Path
+	})
+	return w.files, nil
+}
+
+// walker 保存一次遍历的状态
+type walker struct {
+	opts    WalkOptions
+	rootDev uint64
+	hasDev  bool
+	visited map[string]bool // 已进入的目录的真实路径，避免符号链接造成循环
+	seen    map[string]bool // 已加入的文件
+	files   []FileInfo
+}
+
+// walkDir 遍历目录 dir，rel 为其相对于根目录的路径（"/" 分隔），depth 为其深度
+func (w *walker) walkDir(dir, rel string, depth int) {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		if w.visited[real] {
+			return
+		}
+		w.visited[real] = true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Printf("读取目录 %s 失败: %v\n", dir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		full := filepath.Join(dir, name)
+		entryRel := path.Join(rel, name)
+
+		if !w.opts.IncludeHidden && isTempOrHiddenFile(name) {
+			continue
+		}
+		if matchAny(w.opts.Exclude, entryRel) {
+			continue
+		}
+
+		// 符号链接按策略处理，跟随时使用链接目标的信息
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if w.opts.Symlinks != SymlinkFollow {
+				continue
+			}
+			if info, err = os.Stat(full); err != nil {
+				continue
+			}
+		}
+
+		switch {
+		case info.IsDir():
+			if w.opts.MaxDepth > 0 && depth+1 >= w.opts.MaxDepth {
+				continue
+			}
+			if w.opts.OneFileSystem && w.hasDev {
+				if dev, ok := deviceID(info); ok && dev != w.rootDev {
+					continue
+				}
+			}
+			w.walkDir(full, entryRel, depth+1)
+
+		case info.Mode().IsRegular():
+			if len(w.opts.Include) > 0 && !matchAny(w.opts.Include, entryRel) {
+				continue
+			}
+			if w.seen[full] {
+				continue
+			}
+			w.seen[full] = true
+			w.files = append(w.files, FileInfo{
+				Path:         full,
+				Size:         info.Size(),
+				ModifiedTime: float64(info.ModTime().UnixNano()) / 1e9,
+			})
+		}
+	}
+}
+
+// matchAny 判断相对路径是否匹配任意一个通配符
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob 用通配符匹配相对路径。不含 "/" 的通配符只匹配最后一段
+func matchGlob(pattern, rel string) bool {
+	pattern = filepath.ToSlash(pattern)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments 逐段匹配，"**" 可以匹配零个或多个目录
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// deviceID Windows 下 os.FileInfo 不提供设备编号，不检查文件系统边界
+func deviceID(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
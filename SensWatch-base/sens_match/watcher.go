@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// renameWindow 重命名事件之后等待对应创建事件的时间，超时的重命名视为移出监控范围
+const renameWindow = 200 * time.Millisecond
+
+// pendingRename 表示一个还没有找到目标路径的重命名
+type pendingRename struct {
+	path  string
+	isDir bool
+	at    time.Time
+}
+
+// Watcher 递归监控目录树，把文件系统事件转换为 FileChange。
+// 新建或移入的子目录会自动加入监控。临时文件的事件同样会发出，由 Coalescer 合并后丢弃
+type Watcher struct {
+	fsw     *fsnotify.Watcher
+	dirs    map[string]bool // 正在监控的目录
+	pending []pendingRename
+	changes chan FileChange
+	errors  chan error
+	done    chan struct{}
+}
+
+// NewWatcher 创建监控 roots 下所有目录的 Watcher
+func NewWatcher(roots []string) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监控失败: %v", err)
+	}
+
+	w := &Watcher{
+		fsw:     fsw,
+		dirs:    make(map[string]bool),
+		changes: make(chan FileChange, 1024),
+		errors:  make(chan error, 16),
+		done:    make(chan struct{}),
+	}
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			fsw.Close()
+			return nil, fmt.Errorf("获取目录绝对路径失败: %v", err)
+		}
+		if err := w.addTree(absRoot); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	go w.loop()
+	return w, nil
+}
+
+// Changes 返回文件变化事件通道，Watcher 关闭后通道随之关闭
+func (w *Watcher) Changes() <-chan FileChange {
+	return w.changes
+}
+
+// Errors 返回监控过程中的错误
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close 停止监控
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fsw.Close()
+}
+
+// addTree 监控 root 及其下所有子目录
+func (w *Watcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 遍历过程中目录被删除或无权限访问时跳过
+			if path == root {
+				return fmt.Errorf("监控目录失败: %v", err)
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := w.fsw.Add(path); err != nil {
+			if path == root {
+				return fmt.Errorf("监控目录 %s 失败: %v", path, err)
+			}
+			return nil
+		}
+		w.dirs[path] = true
+		return nil
+	})
+}
+
+// removeTree 取消对 root 及其下所有子目录的监控
+func (w *Watcher) removeTree(root string) {
+	prefix := root + string(filepath.Separator)
+	for dir := range w.dirs {
+		if dir == root || strings.HasPrefix(dir, prefix) {
+			w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+}
+
+// loop 处理文件系统事件，直到 Watcher 关闭
+func (w *Watcher) loop() {
+	defer close(w.changes)
+
+	ticker := time.NewTicker(renameWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			default:
+			}
+		case <-ticker.C:
+			w.expireRenames(time.Now())
+		}
+	}
+}
+
+// handle 把一个文件系统事件转换为 FileChange
+func (w *Watcher) handle(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			w.addTree(path)
+		}
+		// 重命名的目标会以创建事件出现，与同一目录下或同名的最近一次重命名配对。
+		// fsnotify 不提供 inotify 的 cookie，无关的创建事件不能当作移动，
+		// 否则会把数据库中的记录和审计事件改到错误的文件上
+		if i := w.matchRename(path); i >= 0 {
+			from := w.pending[i]
+			w.pending = append(w.pending[:i], w.pending[i+1:]...)
+			w.emitMove(from, path, info.IsDir())
+			return
+		}
+		w.emitCreate(path, info.IsDir())
+
+	case event.Has(fsnotify.Rename):
+		// 被监控的目录本身也会报告一次重命名，只记录一次
+		for _, p := range w.pending {
+			if p.path == path {
+				return
+			}
+		}
+		isDir := w.dirs[path]
+		if isDir {
+			w.removeTree(path)
+		}
+		w.pending = append(w.pending, pendingRename{path: path, isDir: isDir, at: time.Now()})
+
+	case event.Has(fsnotify.Remove):
+		if w.dirs[path] {
+			w.removeTree(path)
+			w.emit(FileChange{Type: "delete", FilePath: path, IsDir: true})
+		} else {
+			w.emit(FileChange{Type: "delete", FilePath: path})
+		}
+
+	case event.Has(fsnotify.Write):
+		if !w.dirs[path] {
+			w.emit(FileChange{Type: "modify", FilePath: path})
+		}
+	}
+}
+
+// matchRename 返回可以与 path 上的创建事件配对的最近一次重命名，没有时返回 -1。
+// 只有同一目录下的重命名或同名文件在目录之间的移动才会配对，其余的重命名超时后按删除处理
+func (w *Watcher) matchRename(path string) int {
+	for i := len(w.pending) - 1; i >= 0; i-- {
+		from := w.pending[i].path
+		if filepath.Dir(from) == filepath.Dir(path) || filepath.Base(from) == filepath.Base(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// emitMove 发送移动事件。目录移动会展开为其中每个文件的移动
+func (w *Watcher) emitMove(from pendingRename, to string, isDir bool) {
+	if !isDir {
+		w.emit(FileChange{Type: "move", OldPath: from.path, NewPath: to})
+		return
+	}
+
+	filepath.WalkDir(to, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(to, path)
+		if err != nil {
+			return nil
+		}
+		w.emit(FileChange{Type: "move", OldPath: filepath.Join(from.path, rel), NewPath: path})
+		return nil
+	})
+}
+
+// emitCreate 发送创建事件。新建或从监控范围外移入的目录会展开为其中每个文件的创建，
+// 目录中在加入监控之前就已写入的文件也不会遗漏
+func (w *Watcher) emitCreate(path string, isDir bool) {
+	if !isDir {
+		w.emit(FileChange{Type: "create", FilePath: path})
+		return
+	}
+
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		w.emit(FileChange{Type: "create", FilePath: p})
+		return nil
+	})
+}
+
+// expireRenames 超时仍未配对的重命名说明文件被移出了监控范围，按删除处理
+func (w *Watcher) expireRenames(now time.Time) {
+	kept := w.pending[:0]
+	for _, p := range w.pending {
+		if now.Sub(p.at) < renameWindow {
+			kept = append(kept, p)
+			continue
+		}
+		w.emit(FileChange{Type: "delete", FilePath: p.path, IsDir: p.isDir})
+	}
+	w.pending = kept
+}
+
+// emit 发送文件变化事件，Watcher 关闭时放弃发送
+func (w *Watcher) emit(change FileChange) {
+	select {
+	case w.changes <- change:
+	case <-w.done:
+	}
+}
+
+// watchRootsFromFiles 从文件列表推导需要监控的目录：取所有文件所在目录中
+// 不被其它目录包含的那些
+func watchRootsFromFiles(files []FileInfo) []string {
+	dirSet := make(map[string]bool)
+	for _, file := range files {
+		if absPath, err := filepath.Abs(file.Path); err == nil {
+			dirSet[filepath.Dir(absPath)] = true
+		}
+	}
+
+	var roots []string
+	for dir := range dirSet {
+		covered := false
+		for child, parent := dir, filepath.Dir(dir); parent != child; child, parent = parent, filepath.Dir(parent) {
+			if dirSet[parent] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			roots = append(roots, dir)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// defaultMaxInFlightBytes 同时处理的文件总大小默认上限
+const defaultMaxInFlightBytes = 512 << 20
+
+// byteBudget 限制同时处理的文件总字节数
+type byteBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int64
+	used  int64
+}
+
+// newByteBudget 创建字节预算，limit <= 0 表示不限制
+func newByteBudget(limit int64) *byteBudget {
+	b := &byteBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire 占用 n 字节，预算不足时阻塞。没有其它文件在处理时，
+// 超过上限的单个大文件也允许通过，避免永远等待
+func (b *byteBudget) acquire(n int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mu.Lock()
+	for b.used > 0 && b.used+n > b.limit {
+		b.cond.Wait()
+	}
+	b.used += n
+	b.mu.Unlock()
+}
+
+// release 归还 n 字节
+func (b *byteBudget) release(n int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// scanJob 表示一个待扫描的文件
+type scanJob struct {
+	index int
+	file  FileInfo
+	size  int64
+}
+
+// scanResult 表示一个文件的扫描结果
+type scanResult struct {
+	index int
+	info  *SensitiveInfo
+	err   error
+}
+
+// SetConcurrency 设置并发扫描的文件数和同时处理的文件总大小上限
+func (p *FileProcessor) SetConcurrency(workers int, maxInFlightBytes int64) {
+	p.workers = workers
+	p.maxInFlightBytes = maxInFlightBytes
+}
+
+// scanFiles 使用有界的工作池并发扫描文件，结果按输入顺序返回
+func (p *FileProcessor) scanFiles(files []FileInfo) []scanResult {
+	workers := p.workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	budget := newByteBudget(p.maxInFlightBytes)
+
+	jobs := make(chan scanJob)
+	results := make(chan scanResult)
+
+	// 按顺序分发任务，预算不足时暂停分发
+	go func() {
+		defer close(jobs)
+		for i, file := range files {
+			size := file.Size
+			if size <= 0 {
+				if stat, err := os.Stat(file.Path); err == nil {
+					size = stat.Size()
+				}
+			}
+			budget.acquire(size)
+			jobs <- scanJob{index: i, file: file, size: size}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				info, err := p.processIncremental(job.file.Path)
+				budget.release(job.size)
+				results <- scanResult{index: job.index, info: info, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// 按输入顺序整理结果，保证输出顺序与并发度无关
+	ordered := make([]scanResult, len(files))
+	for r := range results {
+		ordered[r.index] = r
+	}
+	return ordered
+}